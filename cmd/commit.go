@@ -0,0 +1,456 @@
+package cmd
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/go-github/v52/github"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	fileModeRegular    = "100644"
+	fileModeExecutable = "100755"
+)
+
+// errBranchMoved is returned by commitChanges when the branch was updated
+// by someone else while the commit was being created.
+var errBranchMoved = errors.New("branch was updated meanwhile")
+
+func init() {
+
+	commitCmd.Flags().StringVar(&GithubOrgName, "githubOrgName", "", "name of the github organization")
+	commitCmd.Flags().StringVar(&GithubRepo, "githubRepoName", "", "name of the repository to commit to")
+	commitCmd.Flags().StringVar(&GithubBranchName, "branchName", "", "the name of the branch to commit to")
+	commitCmd.Flags().StringArrayVar(&CommitPuts, "put", nil, "file to add or modify in the form 'remote/path=local/path', can be repeated")
+	commitCmd.Flags().StringArrayVar(&CommitDeletes, "delete", nil, "path of the file to delete, can be repeated")
+	commitCmd.Flags().StringVar(&CommitManifest, "manifest", "", "path to a YAML or JSON file with a list of operations, e.g. [{op: put, path: a.yaml, from: ./a.yaml}, {op: delete, path: b.yaml}]")
+	addCommitFlags(commitCmd)
+	commitCmd.MarkFlagRequired("githubOrgName")
+	commitCmd.MarkFlagRequired("githubRepoName")
+	commitCmd.MarkFlagRequired("branchName")
+	commitCmd.MarkFlagRequired("message")
+
+	commitCmd.Run = func(cmd *cobra.Command, args []string) {
+		if err := Commit(); err != nil {
+			log.Fatalf("error when committing to a github repo: %v", err)
+		}
+	}
+}
+
+// FileChange is a single file addition, modification or deletion
+// that is part of a commit created via the Git Data API. BaseSHA is the
+// blob SHA the change was made against, when set the commit fails with
+// errFileChanged if the file differs in the parent commit. Create makes the
+// commit fail the same way if the file exists in the parent commit. An empty
+// Mode keeps the mode of the file in the parent commit, e.g. the executable bit.
+type FileChange struct {
+	Path    string
+	Content []byte
+	Mode    string
+	Delete  bool
+	BaseSHA string
+	Create  bool
+}
+
+// CommitOperation is a single entry of the manifest given to the commit command.
+type CommitOperation struct {
+	Op      string `yaml:"op"`
+	Path    string `yaml:"path"`
+	From    string `yaml:"from"`
+	Content string `yaml:"content"`
+}
+
+// CommitResult describes a commit created by one of the file commands.
+type CommitResult struct {
+	SHA    string `json:"sha" yaml:"sha"`
+	Branch string `json:"branch" yaml:"branch"`
+	URL    string `json:"url" yaml:"url"`
+	Files  int    `json:"files" yaml:"files"`
+}
+
+func Commit() error {
+
+	var changes []*FileChange
+	for _, put := range CommitPuts {
+		remote, local, ok := strings.Cut(put, "=")
+		if !ok || remote == "" || local == "" {
+			return fmt.Errorf("invalid value '%s' of the 'put' parameter, expected 'remote/path=local/path'", put)
+		}
+		change, err := localFileChange(remote, local)
+		if err != nil {
+			return err
+		}
+		changes = append(changes, change)
+	}
+	for _, path := range CommitDeletes {
+		changes = append(changes, &FileChange{Path: path, Delete: true})
+	}
+	if CommitManifest != "" {
+		manifestChanges, err := readCommitManifest(CommitManifest)
+		if err != nil {
+			return err
+		}
+		changes = append(changes, manifestChanges...)
+	}
+	if len(changes) == 0 {
+		return fmt.Errorf("none of the parameters 'put', 'delete' or 'manifest' specified")
+	}
+
+	var paths []string
+	for _, c := range changes {
+		paths = append(paths, c.Path)
+	}
+	message, err := commitMessage("", strings.Join(paths, ", "), GithubBranchName)
+	if err != nil {
+		return err
+	}
+
+	var commit *github.Commit
+	err = withRetries(func() error {
+		// the changes are applied on top of the current head on every attempt
+		commit, err = commitChanges(context.Background(), GithubBranchName, changes, message)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return printCommitResult(&CommitResult{SHA: commit.GetSHA(), Branch: GithubBranchName, URL: commit.GetHTMLURL(), Files: len(changes)})
+}
+
+func printCommitResult(result *CommitResult) error {
+	return writeOutput(result, func() {
+		fmt.Printf("commit %s with %d changed files pushed to branch %s\n", result.SHA, result.Files, result.Branch)
+	})
+}
+
+func readCommitManifest(path string) ([]*FileChange, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error when reading manifest: %v", err)
+	}
+	// JSON is a subset of YAML, so both are parsed the same way
+	var ops []CommitOperation
+	if err := yaml.Unmarshal(data, &ops); err != nil {
+		return nil, fmt.Errorf("error when parsing manifest %s: %v", path, err)
+	}
+
+	var changes []*FileChange
+	for i, op := range ops {
+		if op.Path == "" {
+			return nil, fmt.Errorf("operation #%d in %s has no path", i+1, path)
+		}
+		switch op.Op {
+		case "put", "add", "modify":
+			if op.From == "" {
+				changes = append(changes, &FileChange{Path: op.Path, Content: []byte(op.Content), Mode: fileModeRegular})
+				continue
+			}
+			change, err := localFileChange(op.Path, op.From)
+			if err != nil {
+				return nil, err
+			}
+			changes = append(changes, change)
+		case "delete":
+			changes = append(changes, &FileChange{Path: op.Path, Delete: true})
+		default:
+			return nil, fmt.Errorf("unsupported operation '%s' of #%d in %s", op.Op, i+1, path)
+		}
+	}
+	return changes, nil
+}
+
+// localFileChange reads a local file that should be committed to the remote path.
+func localFileChange(remote, local string) (*FileChange, error) {
+	info, err := os.Stat(local)
+	if err != nil {
+		return nil, fmt.Errorf("error when reading local file: %v", err)
+	}
+	content, err := os.ReadFile(local)
+	if err != nil {
+		return nil, fmt.Errorf("error when reading local file: %v", err)
+	}
+	mode := fileModeRegular
+	if info.Mode()&0111 != 0 {
+		mode = fileModeExecutable
+	}
+	return &FileChange{Path: strings.TrimPrefix(remote, "/"), Content: content, Mode: mode}, nil
+}
+
+// withRetries runs the update again when it failed because the file or the
+// branch was updated concurrently, so the update can re-read the current
+// state and re-apply the change on top of it.
+func withRetries(update func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := update()
+		if err == nil || !(errors.Is(err, errBranchMoved) || errors.Is(err, errFileChanged)) || attempt > MaxRetries {
+			return err
+		}
+		log.Printf("conflicting update, retrying (%d/%d): %v", attempt, MaxRetries, err)
+		time.Sleep(time.Duration(attempt) * time.Second)
+	}
+}
+
+// CommitMessageData holds the variables available in commit message templates.
+type CommitMessageData struct {
+	Path   string
+	Branch string
+	Date   string
+}
+
+// addCommitFlags registers the flags controlling the commits made by the file commands.
+func addCommitFlags(c *cobra.Command) {
+	c.Flags().StringVar(&CommitMessage, "message", "", "the commit message, can use the {{.Path}}, {{.Branch}} and {{.Date}} template variables")
+	c.Flags().StringVar(&AuthorName, "author-name", "", "name of the commit author - defaults to the token owner")
+	c.Flags().StringVar(&AuthorEmail, "author-email", "", "email of the commit author - defaults to the token owner")
+	c.Flags().StringVar(&CommitterName, "committer-name", "", "name of the committer - defaults to the author")
+	c.Flags().StringVar(&CommitterEmail, "committer-email", "", "email of the committer - defaults to the author")
+	c.Flags().IntVar(&MaxRetries, "max-retries", 3, "how many times to re-apply the change when the file or branch was updated concurrently")
+	c.Flags().StringVar(&SignKeyPath, "sign-key", "", "path to an armored GPG or an OpenSSH private key to sign the commit with, the commit is then created via the Git Data API")
+	c.Flags().StringVar(&SignPassphrase, "sign-passphrase", "", fmt.Sprintf("passphrase of the signing key. Can be set via the %s env var.", strings.ToUpper(SignPassKey)))
+}
+
+// commitMessage renders the message given via --message, or the default
+// message when it was not specified.
+func commitMessage(defaultMessage, path, branch string) (string, error) {
+	message := CommitMessage
+	if message == "" {
+		message = defaultMessage
+	}
+	tmpl, err := template.New("message").Option("missingkey=error").Parse(message)
+	if err != nil {
+		return "", fmt.Errorf("problem with commit message template: %v", err)
+	}
+	var buf strings.Builder
+	err = tmpl.Execute(&buf, CommitMessageData{Path: path, Branch: branch, Date: time.Now().Format("2006-01-02")})
+	if err != nil {
+		return "", fmt.Errorf("problem with commit message template: %v", err)
+	}
+	return buf.String(), nil
+}
+
+// commitAuthor returns the author given via flags, or nil to let github
+// use the token owner.
+func commitAuthor() (*github.CommitAuthor, error) {
+	return commitIdentity("author", AuthorName, AuthorEmail)
+}
+
+// commitCommitter returns the committer given via flags, or nil to let github
+// use the author.
+func commitCommitter() (*github.CommitAuthor, error) {
+	return commitIdentity("committer", CommitterName, CommitterEmail)
+}
+
+func commitIdentity(role, name, email string) (*github.CommitAuthor, error) {
+	if name == "" && email == "" {
+		return nil, nil
+	}
+	if name == "" || email == "" {
+		return nil, fmt.Errorf("both the parameters '%s-name' and '%s-email' have to be specified", role, role)
+	}
+	// the signed payload has a second precision, so the date must not carry more
+	return &github.CommitAuthor{Name: github.String(name), Email: github.String(email), Date: &github.Timestamp{Time: time.Now().Truncate(time.Second)}}, nil
+}
+
+// commitChanges creates a single commit with all the changes on top of the
+// branch and fast-forwards the branch to it. errBranchMoved is returned when
+// the branch does not point to the parent commit anymore.
+func commitChanges(ctx context.Context, branch string, changes []*FileChange, message string) (*github.Commit, error) {
+
+	author, err := commitAuthor()
+	if err != nil {
+		return nil, err
+	}
+	committer, err := commitCommitter()
+	if err != nil {
+		return nil, err
+	}
+	signer, err := loadCommitSigner()
+	if err != nil {
+		return nil, err
+	}
+	if signer != nil && author == nil {
+		// the signed payload has to contain the exact author github will store
+		if author, err = signer.author(); err != nil {
+			return nil, err
+		}
+	}
+
+	ref, _, err := GithubClient.Git.GetRef(ctx, GithubOrgName, GithubRepo, fmt.Sprintf("heads/%s", branch))
+	if err != nil {
+		return nil, fmt.Errorf("error getting branch %s: %+v", branch, err)
+	}
+	parentSHA := ref.GetObject().GetSHA()
+
+	parent, _, err := GithubClient.Git.GetCommit(ctx, GithubOrgName, GithubRepo, parentSHA)
+	if err != nil {
+		return nil, fmt.Errorf("error getting commit %s: %+v", parentSHA, err)
+	}
+
+	for _, c := range changes {
+		if c.BaseSHA == "" && !c.Create {
+			continue
+		}
+		file, _, res, err := GithubClient.Repositories.GetContents(ctx, GithubOrgName, GithubRepo, c.Path, &github.RepositoryContentGetOptions{Ref: parentSHA})
+		if err != nil {
+			if res != nil && res.StatusCode == http.StatusNotFound {
+				if c.Create {
+					continue
+				}
+				return nil, fmt.Errorf("%w: %s was deleted", errFileChanged, c.Path)
+			}
+			return nil, fmt.Errorf("error when listing file contents: %v", err)
+		}
+		if c.Create {
+			return nil, fmt.Errorf("%w: %s was created", errFileChanged, c.Path)
+		}
+		if file.GetSHA() != c.BaseSHA {
+			return nil, fmt.Errorf("%w: %s is %s instead of %s", errFileChanged, c.Path, file.GetSHA(), c.BaseSHA)
+		}
+	}
+
+	var entries []*github.TreeEntry
+	for _, c := range changes {
+		if c.Delete {
+			// an entry without SHA and content removes the path from the tree
+			entries = append(entries, &github.TreeEntry{Path: github.String(c.Path), Mode: github.String(fileModeRegular), Type: github.String("blob")})
+			continue
+		}
+		blob, _, err := GithubClient.Git.CreateBlob(ctx, GithubOrgName, GithubRepo, &github.Blob{
+			Content:  github.String(base64.StdEncoding.EncodeToString(c.Content)),
+			Encoding: github.String("base64"),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error when creating blob for %s: %v", c.Path, err)
+		}
+		mode := c.Mode
+		if mode == "" {
+			if mode, err = treeEntryMode(ctx, parent.GetTree().GetSHA(), c.Path); err != nil {
+				return nil, err
+			}
+		}
+		if mode == "" {
+			mode = fileModeRegular
+		}
+		entries = append(entries, &github.TreeEntry{Path: github.String(c.Path), Mode: github.String(mode), Type: github.String("blob"), SHA: blob.SHA})
+	}
+
+	tree, _, err := GithubClient.Git.CreateTree(ctx, GithubOrgName, GithubRepo, parent.GetTree().GetSHA(), entries)
+	if err != nil {
+		return nil, fmt.Errorf("error when creating tree: %v", err)
+	}
+
+	newCommit := &github.Commit{
+		Message:   github.String(message),
+		Tree:      &github.Tree{SHA: tree.SHA},
+		Parents:   []*github.Commit{{SHA: github.String(parentSHA)}},
+		Author:    author,
+		Committer: committer,
+	}
+	if signer != nil {
+		if err := signer.sign(newCommit); err != nil {
+			return nil, err
+		}
+	}
+	commit, _, err := GithubClient.Git.CreateCommit(ctx, GithubOrgName, GithubRepo, newCommit)
+	if err != nil {
+		return nil, fmt.Errorf("error when creating commit: %v", err)
+	}
+	if signer != nil {
+		log.Printf("signed commit %s verified: %t (%s)", commit.GetSHA(), commit.GetVerification().GetVerified(), commit.GetVerification().GetReason())
+	}
+
+	current, _, err := GithubClient.Git.GetRef(ctx, GithubOrgName, GithubRepo, fmt.Sprintf("heads/%s", branch))
+	if err != nil {
+		return nil, fmt.Errorf("error getting branch %s: %+v", branch, err)
+	}
+	if current.GetObject().GetSHA() != parentSHA {
+		return nil, fmt.Errorf("%w: %s now points to %s instead of %s", errBranchMoved, branch, current.GetObject().GetSHA(), parentSHA)
+	}
+
+	ref.Object.SHA = commit.SHA
+	_, res, err := GithubClient.Git.UpdateRef(ctx, GithubOrgName, GithubRepo, ref, false)
+	if err != nil {
+		// github rejects updates that are not a fast-forward with 422, as well
+		// as the ones refused by branch protection which must not be retried
+		if isValidationError(res, err, "Update is not a fast forward") {
+			return nil, fmt.Errorf("%w: %s is not a fast-forward of %s anymore: %v", errBranchMoved, commit.GetSHA(), branch, err)
+		}
+		return nil, fmt.Errorf("error when updating branch %s: %v", branch, err)
+	}
+
+	log.Printf("branch %s updated: %s -> %s", branch, parentSHA, commit.GetSHA())
+	return commit, nil
+}
+
+// resolveBranch returns the branch given via --branchName, or the default
+// branch of the repository when it was not specified.
+func resolveBranch(ctx context.Context) (string, error) {
+	if GithubBranchName != "" {
+		return GithubBranchName, nil
+	}
+	repo, _, err := GithubClient.Repositories.Get(ctx, GithubOrgName, GithubRepo)
+	if err != nil {
+		return "", fmt.Errorf("error when getting repository %s/%s: %v", GithubOrgName, GithubRepo, err)
+	}
+	return repo.GetDefaultBranch(), nil
+}
+
+// remoteTreeFiles returns all the files on the branch keyed by their path.
+func remoteTreeFiles(ctx context.Context, branch string) (map[string]*github.TreeEntry, error) {
+	tree, _, err := GithubClient.Git.GetTree(ctx, GithubOrgName, GithubRepo, fmt.Sprintf("heads/%s", branch), true)
+	if err != nil {
+		return nil, fmt.Errorf("error when getting tree of branch %s: %v", branch, err)
+	}
+	if tree.GetTruncated() {
+		return nil, fmt.Errorf("tree of branch %s is too large to be listed at once", branch)
+	}
+
+	files := map[string]*github.TreeEntry{}
+	for _, e := range tree.Entries {
+		if e.GetType() == "blob" {
+			files[e.GetPath()] = e
+		}
+	}
+	return files, nil
+}
+
+// treeEntryMode returns the mode of the file in the tree, or an empty string
+// when there is no such file. The tree is walked one directory at a time, so
+// large repositories are not listed at once.
+func treeEntryMode(ctx context.Context, treeSHA, filePath string) (string, error) {
+	names := strings.Split(strings.Trim(filePath, "/"), "/")
+	for i, name := range names {
+		tree, _, err := GithubClient.Git.GetTree(ctx, GithubOrgName, GithubRepo, treeSHA, false)
+		if err != nil {
+			return "", fmt.Errorf("error when getting tree %s: %v", treeSHA, err)
+		}
+		var entry *github.TreeEntry
+		for _, e := range tree.Entries {
+			if e.GetPath() == name {
+				entry = e
+				break
+			}
+		}
+		switch {
+		case entry == nil:
+			return "", nil
+		case i == len(names)-1 && entry.GetType() == "blob":
+			return entry.GetMode(), nil
+		case entry.GetType() != "tree":
+			return "", nil
+		}
+		treeSHA = entry.GetSHA()
+	}
+	return "", nil
+}
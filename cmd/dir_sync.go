@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+
+	dirSync.Flags().StringVar(&GithubOrgName, "githubOrgName", "", "name of the github organization")
+	dirSync.Flags().StringVar(&GithubRepo, "githubRepoName", "", "name of the repo to sync the directory to")
+	dirSync.Flags().StringVar(&GithubBranchName, "branch", "", "the name of the branch to sync to - defaults to the default branch")
+	dirSync.Flags().StringVar(&SyncLocalDir, "local", "", "path to the local directory")
+	dirSync.Flags().StringVar(&SyncRemoteDir, "remote", "", "path to the directory in the repo, the repository root when empty")
+	addCommitFlags(dirSync)
+	dirSync.Flags().BoolVar(&SyncDelete, "delete", false, "delete remote files that do not exist locally")
+	dirSync.Flags().BoolVar(&DryRun, "dryRun", false, "only print the changes that would be committed")
+	dirSync.MarkFlagRequired("githubOrgName")
+	dirSync.MarkFlagRequired("githubRepoName")
+	dirSync.MarkFlagRequired("local")
+
+	dirSync.Run = func(cmd *cobra.Command, args []string) {
+		if err := SyncDir(); err != nil {
+			log.Fatalf("error when syncing directory to a github repo: %v", err)
+		}
+	}
+}
+
+func SyncDir() error {
+
+	remoteDir := strings.Trim(SyncRemoteDir, "/")
+	local, err := localDirChanges(SyncLocalDir, remoteDir)
+	if err != nil {
+		return err
+	}
+
+	var results []*FileResult
+	err = withRetries(func() error {
+		// the diff is recomputed against the current remote tree on every attempt
+		results, err = syncDir(context.Background(), local, remoteDir)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return printFileResults(results)
+}
+
+func syncDir(ctx context.Context, local []*FileChange, remoteDir string) ([]*FileResult, error) {
+
+	branch, err := resolveBranch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	remote, err := remoteTreeFiles(ctx, branch)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*FileResult
+	var changes []*FileChange
+	localPaths := map[string]bool{}
+	for _, c := range local {
+		localPaths[c.Path] = true
+		action := FileCreated
+		if entry, ok := remote[c.Path]; ok {
+			// files are compared by their git blob SHA, so nothing has to be downloaded
+			if entry.GetSHA() == gitBlobSHA(c.Content) && entry.GetMode() == c.Mode {
+				continue
+			}
+			action = FileUpdated
+		}
+		changes = append(changes, c)
+		results = append(results, &FileResult{Path: c.Path, Branch: branch, Action: action})
+	}
+
+	if SyncDelete {
+		prefix := remoteDir
+		if prefix != "" {
+			prefix += "/"
+		}
+		var deleted []string
+		for p := range remote {
+			if strings.HasPrefix(p, prefix) && !localPaths[p] {
+				deleted = append(deleted, p)
+			}
+		}
+		sort.Strings(deleted)
+		for _, p := range deleted {
+			changes = append(changes, &FileChange{Path: p, Delete: true})
+			results = append(results, &FileResult{Path: p, Branch: branch, Action: FileDeleted})
+		}
+	}
+
+	if len(changes) == 0 {
+		log.Printf("%s is already in sync with %s on branch %s", SyncLocalDir, "/"+remoteDir, branch)
+		return results, nil
+	}
+	if DryRun {
+		return results, nil
+	}
+
+	message, err := commitMessage("Sync {{.Path}}", "/"+remoteDir, branch)
+	if err != nil {
+		return nil, err
+	}
+	commit, err := commitChanges(ctx, branch, changes, message)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range results {
+		r.CommitSHA = commit.GetSHA()
+	}
+	return results, nil
+}
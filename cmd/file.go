@@ -2,8 +2,18 @@ package cmd
 
 import (
 	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
 
 	"github.com/google/go-github/v52/github"
 	"github.com/spf13/cobra"
@@ -15,14 +25,18 @@ func init() {
 	fileCreate.Flags().StringVar(&GithubRepo, "githubRepoName", "", "name of the repo where the hook should be set up")
 	fileCreate.Flags().StringVar(&FilePath, "filePath", "", "path to the file that should be updated")
 	fileCreate.Flags().StringVar(&FileContent, "fileContent", "", "new content of the file")
+	fileCreate.Flags().StringVar(&FileFromPath, "from-file", "", "path to a local file with the new content, or a directory to put recursively under filePath")
+	fileCreate.Flags().BoolVar(&FileFromStdin, "from-stdin", false, "read the new content of the file from stdin")
 	fileCreate.Flags().StringVar(&GithubBranchName, "branchName", "", "the name of the branch to update")
+	addCommitFlags(fileCreate)
 	fileCreate.MarkFlagRequired("githubOrgName")
 	fileCreate.MarkFlagRequired("githubRepoName")
 	fileCreate.MarkFlagRequired("filePath")
-	fileCreate.MarkFlagRequired("fileContent")
 
 	fileCreate.Run = func(cmd *cobra.Command, args []string) {
-		if err := UpdateFile(); err != nil {
+		// an empty content is valid, so only the flag being set counts
+		FileContentSet = cmd.Flags().Changed("fileContent")
+		if err := PutFile(); err != nil {
 			log.Fatalf("error when creating a file in a github repo: %v", err)
 		}
 	}
@@ -31,22 +45,45 @@ func init() {
 	fileUpdate.Flags().StringVar(&GithubRepo, "githubRepoName", "", "name of the repo where the hook should be set up")
 	fileUpdate.Flags().StringVar(&FilePath, "filePath", "", "path to the file that should be updated")
 	fileUpdate.Flags().StringVar(&FileContent, "fileContent", "", "new content of the file")
+	fileUpdate.Flags().StringVar(&FileFromPath, "from-file", "", "path to a local file with the new content, or a directory to put recursively under filePath")
+	fileUpdate.Flags().BoolVar(&FileFromStdin, "from-stdin", false, "read the new content of the file from stdin")
 	fileUpdate.Flags().StringVar(&GithubBranchName, "branchName", "", "the name of the branch to update")
+	addCommitFlags(fileUpdate)
 	fileUpdate.MarkFlagRequired("githubOrgName")
 	fileUpdate.MarkFlagRequired("githubRepoName")
 	fileUpdate.MarkFlagRequired("filePath")
-	fileUpdate.MarkFlagRequired("fileContent")
 
 	fileUpdate.Run = func(cmd *cobra.Command, args []string) {
-		if err := UpdateFile(); err != nil {
+		FileContentSet = cmd.Flags().Changed("fileContent")
+		if err := PutFile(); err != nil {
 			log.Fatalf("error when committing to a github repo: %v", err)
 		}
 	}
 
+	filePut.Flags().StringVar(&GithubOrgName, "githubOrgName", "", "name of the github organization")
+	filePut.Flags().StringVar(&GithubRepo, "githubRepoName", "", "name of the repo the file should be put to")
+	filePut.Flags().StringVar(&FilePath, "filePath", "", "path to the file that should be created or updated")
+	filePut.Flags().StringVar(&FileContent, "fileContent", "", "new content of the file")
+	filePut.Flags().StringVar(&FileFromPath, "from-file", "", "path to a local file with the new content, or a directory to put recursively under filePath")
+	filePut.Flags().BoolVar(&FileFromStdin, "from-stdin", false, "read the new content of the file from stdin")
+	filePut.Flags().StringVar(&GithubBranchName, "branchName", "", "the name of the branch to update - defaults to the default branch")
+	addCommitFlags(filePut)
+	filePut.MarkFlagRequired("githubOrgName")
+	filePut.MarkFlagRequired("githubRepoName")
+	filePut.MarkFlagRequired("filePath")
+
+	filePut.Run = func(cmd *cobra.Command, args []string) {
+		FileContentSet = cmd.Flags().Changed("fileContent")
+		if err := PutFile(); err != nil {
+			log.Fatalf("error when putting a file to a github repo: %v", err)
+		}
+	}
+
 	fileDelete.Flags().StringVar(&GithubOrgName, "githubOrgName", "", "name of the github organization")
 	fileDelete.Flags().StringVar(&GithubRepo, "githubRepoName", "", "name of the repo where the hook should be set up")
 	fileDelete.Flags().StringVar(&FilePath, "filePath", "", "path to the file that should be updated")
 	fileDelete.Flags().StringVar(&GithubBranchName, "branchName", "", "the name of the branch to update")
+	addCommitFlags(fileDelete)
 	fileDelete.MarkFlagRequired("githubOrgName")
 	fileDelete.MarkFlagRequired("githubRepoName")
 	fileDelete.MarkFlagRequired("filePath")
@@ -58,73 +95,437 @@ func init() {
 	}
 }
 
-func UpdateFile() error {
+const (
+	FileCreated   = "created"
+	FileUpdated   = "updated"
+	FileUnchanged = "unchanged"
+	FileDeleted   = "deleted"
+)
+
+// FileResult describes what one of the file commands did with a file.
+type FileResult struct {
+	Path      string `json:"path" yaml:"path"`
+	Branch    string `json:"branch,omitempty" yaml:"branch,omitempty"`
+	Action    string `json:"action" yaml:"action"`
+	CommitSHA string `json:"commitSha,omitempty" yaml:"commitSha,omitempty"`
+}
+
+// errFileChanged is returned when the file was updated by someone else
+// between reading its SHA and committing the change.
+var errFileChanged = errors.New("file was updated meanwhile")
+
+// contentsAPILimit is the size of the largest file handled via the Contents API,
+// larger files are committed as blobs via the Git Data API.
+const contentsAPILimit = 1024 * 1024
+
+func PutFile() error {
 
 	ctx := context.Background()
 
-	opts := &github.RepositoryContentGetOptions{}
-	if GithubBranchName != "" {
-		opts.Ref = fmt.Sprintf("heads/%s", GithubBranchName)
+	sources := 0
+	for _, set := range []bool{FileContentSet, FileFromPath != "", FileFromStdin} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return fmt.Errorf("exactly one of the parameters 'fileContent', 'from-file' or 'from-stdin' has to be specified")
+	}
+
+	var content []byte
+	switch {
+	case FileFromStdin:
+		var err error
+		if content, err = io.ReadAll(os.Stdin); err != nil {
+			return fmt.Errorf("error when reading stdin: %v", err)
+		}
+	case FileFromPath != "":
+		info, err := os.Stat(FileFromPath)
+		if err != nil {
+			return fmt.Errorf("error when reading local file: %v", err)
+		}
+		if info.IsDir() {
+			changes, err := localDirChanges(FileFromPath, FilePath)
+			if err != nil {
+				return err
+			}
+			var results []*FileResult
+			err = withRetries(func() error {
+				results, err = putFiles(ctx, changes)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			return printFileResults(results)
+		}
+		if content, err = os.ReadFile(FileFromPath); err != nil {
+			return fmt.Errorf("error when reading local file: %v", err)
+		}
+	default:
+		content = []byte(FileContent)
+	}
+
+	var result *FileResult
+	err := withRetries(func() error {
+		var err error
+		result, err = putFile(ctx, FilePath, content)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return printFileResults([]*FileResult{result})
+}
+
+// localDirChanges walks the local directory recursively and returns a change
+// for every file in it, placed under the remote directory.
+func localDirChanges(localDir, remoteDir string) ([]*FileChange, error) {
+	var changes []*FileChange
+	err := filepath.WalkDir(localDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(localDir, p)
+		if err != nil {
+			return err
+		}
+		change, err := localFileChange(path.Join(remoteDir, filepath.ToSlash(rel)), p)
+		if err != nil {
+			return err
+		}
+		changes = append(changes, change)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error when reading local directory %s: %v", localDir, err)
+	}
+	return changes, nil
+}
+
+// putFiles commits all the changed files in a single commit via the Git Data API.
+func putFiles(ctx context.Context, changes []*FileChange) ([]*FileResult, error) {
+
+	branch, err := resolveBranch(ctx)
+	if err != nil {
+		return nil, err
 	}
-	file, _, _, err := GithubClient.Repositories.GetContents(ctx, GithubOrgName, GithubRepo, "README.md", opts)
+	remote, err := remoteTreeFiles(ctx, branch)
 	if err != nil {
-		return fmt.Errorf("error when listing file contents: %v", err)
+		return nil, err
 	}
 
-	fileSha := file.GetSHA()
+	var results []*FileResult
+	var changed []*FileChange
+	for _, c := range changes {
+		result := &FileResult{Path: c.Path, Branch: branch, Action: FileCreated}
+		results = append(results, result)
+		if entry, ok := remote[c.Path]; ok {
+			if entry.GetSHA() == gitBlobSHA(c.Content) && entry.GetMode() == c.Mode {
+				result.Action = FileUnchanged
+				continue
+			}
+			result.Action = FileUpdated
+		}
+		changed = append(changed, c)
+	}
+	if len(changed) == 0 {
+		return results, nil
+	}
 
-	update := &github.RepositoryContentFileOptions{
-		Message: github.String("test commit message"),
-		SHA:     github.String(fileSha),
-		Content: []byte(FileContent),
-		Branch:  github.String(GithubBranchName),
+	message, err := commitMessage("Update {{.Path}}", FilePath, branch)
+	if err != nil {
+		return nil, err
 	}
-	_, _, err = GithubClient.Repositories.UpdateFile(ctx, GithubOrgName, GithubRepo, FilePath, update)
+	commit, err := commitChanges(ctx, branch, changed, message)
 	if err != nil {
-		return fmt.Errorf("error when updating a file on github: %v", err)
+		return nil, err
+	}
+	for _, r := range results {
+		if r.Action != FileUnchanged {
+			r.CommitSHA = commit.GetSHA()
+		}
 	}
+	return results, nil
+}
 
-	return nil
+func printFileResults(results []*FileResult) error {
+	return writeOutput(results, func() {
+		for _, r := range results {
+			if r.CommitSHA == "" {
+				fmt.Printf("%s %s\n", r.Path, r.Action)
+				continue
+			}
+			fmt.Printf("%s %s in commit %s\n", r.Path, r.Action, r.CommitSHA)
+		}
+	})
 }
 
-func DeleteFile() error {
+// putFile creates the file when it does not exist on the branch yet, updates
+// it when its content differs and does nothing otherwise.
+func putFile(ctx context.Context, path string, content []byte) (*FileResult, error) {
 
-	getOpts := &github.RepositoryContentGetOptions{}
-	if GithubBranchName != "" {
-		getOpts.Ref = fmt.Sprintf("heads/%s", GithubBranchName)
+	file, err := getFile(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return writeFile(ctx, path, content, file)
+}
+
+// writeFile creates or updates the file based on its version read before,
+// file is nil when it did not exist. errFileChanged is returned when the
+// file was changed since it was read.
+func writeFile(ctx context.Context, path string, content []byte, file *github.RepositoryContent) (*FileResult, error) {
+
+	// the Contents API can neither handle large files nor sign commits
+	if len(content) > contentsAPILimit || SignKeyPath != "" {
+		return putFileViaGitData(ctx, path, content, file)
+	}
+
+	branch, err := resolveBranch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := &FileResult{Path: path, Branch: branch}
+
+	opts, err := contentFileOptions()
+	if err != nil {
+		return nil, err
+	}
+	opts.Content = content
+
+	var contentResp *github.RepositoryContentResponse
+	if file == nil {
+		if opts.Message, err = contentMessage("Create {{.Path}}", path, branch); err != nil {
+			return nil, err
+		}
+		var res *github.Response
+		contentResp, res, err = GithubClient.Repositories.CreateFile(ctx, GithubOrgName, GithubRepo, path, opts)
+		if err != nil {
+			if isFileExistsError(res, err) {
+				return nil, fmt.Errorf("%w: %s: %v", errFileChanged, path, err)
+			}
+			return nil, fmt.Errorf("error when creating a file on github: %v", err)
+		}
+		result.Action = FileCreated
+	} else {
+		// the content API returns the git blob SHA, so there is no need to compare the content itself
+		if file.GetSHA() == gitBlobSHA(content) {
+			result.Action = FileUnchanged
+			return result, nil
+		}
+		if opts.Message, err = contentMessage("Update {{.Path}}", path, branch); err != nil {
+			return nil, err
+		}
+		opts.SHA = file.SHA
+		var res *github.Response
+		contentResp, res, err = GithubClient.Repositories.UpdateFile(ctx, GithubOrgName, GithubRepo, path, opts)
+		if err != nil {
+			if isContentConflict(res) {
+				return nil, fmt.Errorf("%w: %s: %v", errFileChanged, path, err)
+			}
+			return nil, fmt.Errorf("error when updating a file on github: %v", err)
+		}
+		result.Action = FileUpdated
+	}
+
+	result.CommitSHA = contentResp.Commit.GetSHA()
+	return result, nil
+}
+
+// putFileViaGitData commits the file as a blob via the Git Data API.
+func putFileViaGitData(ctx context.Context, path string, content []byte, file *github.RepositoryContent) (*FileResult, error) {
+
+	branch, err := resolveBranch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &FileResult{Path: path, Branch: branch, Action: FileCreated}
+	defaultMessage := "Create {{.Path}}"
+	if file != nil {
+		if file.GetSHA() == gitBlobSHA(content) {
+			result.Action = FileUnchanged
+			return result, nil
+		}
+		result.Action = FileUpdated
+		defaultMessage = "Update {{.Path}}"
+	}
+
+	message, err := commitMessage(defaultMessage, path, branch)
+	if err != nil {
+		return nil, err
+	}
+	// an updated file keeps its mode, e.g. the executable bit
+	mode := ""
+	if file == nil {
+		mode = fileModeRegular
 	}
-	file, _, resp, err := GithubClient.Repositories.GetContents(context.Background(), GithubOrgName, GithubRepo, FilePath, getOpts)
+	commit, err := commitChanges(ctx, branch, []*FileChange{{Path: path, Content: content, Mode: mode, BaseSHA: file.GetSHA(), Create: file == nil}}, message)
 	if err != nil {
-		log.Printf("resp content: %+v", resp.StatusCode)
-		return fmt.Errorf("error when listing file contents: %v", err)
+		return nil, err
 	}
+	result.CommitSHA = commit.GetSHA()
+	return result, nil
+}
+
+// contentFileOptions returns the Contents API options with the branch,
+// author and committer given via flags.
+func contentFileOptions() (*github.RepositoryContentFileOptions, error) {
+	opts := &github.RepositoryContentFileOptions{}
+	if GithubBranchName != "" {
+		opts.Branch = github.String(GithubBranchName)
+	}
+	var err error
+	if opts.Author, err = commitAuthor(); err != nil {
+		return nil, err
+	}
+	if opts.Committer, err = commitCommitter(); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
 
-	deleteOpts := &github.RepositoryContentFileOptions{
-		Message: github.String("test delete"),
-		SHA:     github.String(file.GetSHA()),
+// contentMessage renders the commit message for the Contents API, the branch
+// has to be resolved so the template does not render an empty branch when
+// the default branch is used.
+func contentMessage(defaultMessage, path, branch string) (*string, error) {
+	message, err := commitMessage(defaultMessage, path, branch)
+	if err != nil {
+		return nil, err
 	}
+	return github.String(message), nil
+}
+
+// getFile returns the file at the path on the branch, or nil when there is no such file.
+func getFile(ctx context.Context, path string) (*github.RepositoryContent, error) {
+	opts := &github.RepositoryContentGetOptions{}
 	if GithubBranchName != "" {
-		deleteOpts.Branch = github.String(GithubBranchName)
+		opts.Ref = fmt.Sprintf("heads/%s", GithubBranchName)
+	}
+	file, dir, resp, err := GithubClient.Repositories.GetContents(ctx, GithubOrgName, GithubRepo, path, opts)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error when listing file contents: %v", err)
+	}
+	if file == nil && dir != nil {
+		return nil, fmt.Errorf("%s is a directory", path)
 	}
-	contentResp, _, err := GithubClient.Repositories.DeleteFile(context.Background(), GithubOrgName, GithubRepo, FilePath, deleteOpts)
+	return file, nil
+}
+
+// gitBlobSHA computes the SHA git uses for a blob with the given content.
+func gitBlobSHA(content []byte) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", len(content))
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func DeleteFile() error {
+
+	var result *FileResult
+	err := withRetries(func() error {
+		var err error
+		result, err = deleteFile(context.Background(), FilePath)
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("error when deleting file on github: %v", err)
+		return err
 	}
-	log.Printf("content resp: %+v", contentResp)
-	return nil
+
+	return printFileResults([]*FileResult{result})
 }
 
-func CreateFile() error {
-	opts := &github.RepositoryContentFileOptions{
-		Message: github.String("e2e test commit message"),
-		Content: []byte(FileContent),
-		Branch:  github.String("rhtap-demo-component-cwac"),
+func deleteFile(ctx context.Context, path string) (*FileResult, error) {
+
+	file, err := getFile(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if file == nil {
+		return nil, fmt.Errorf("file %s not found", path)
+	}
+
+	if SignKeyPath != "" {
+		branch, err := resolveBranch(ctx)
+		if err != nil {
+			return nil, err
+		}
+		message, err := commitMessage("Delete {{.Path}}", path, branch)
+		if err != nil {
+			return nil, err
+		}
+		commit, err := commitChanges(ctx, branch, []*FileChange{{Path: path, Delete: true, BaseSHA: file.GetSHA()}}, message)
+		if err != nil {
+			return nil, err
+		}
+		return &FileResult{Path: path, Branch: branch, Action: FileDeleted, CommitSHA: commit.GetSHA()}, nil
 	}
 
-	_, _, err := GithubClient.Repositories.CreateFile(context.Background(), GithubOrgName, GithubRepo, FilePath, opts)
+	branch, err := resolveBranch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	deleteOpts, err := contentFileOptions()
+	if err != nil {
+		return nil, err
+	}
+	deleteOpts.SHA = file.SHA
+	if deleteOpts.Message, err = contentMessage("Delete {{.Path}}", path, branch); err != nil {
+		return nil, err
+	}
+	contentResp, res, err := GithubClient.Repositories.DeleteFile(ctx, GithubOrgName, GithubRepo, path, deleteOpts)
 	if err != nil {
-		return fmt.Errorf("error when creating file contents: %v", err)
+		if isContentConflict(res) {
+			return nil, fmt.Errorf("%w: %s: %v", errFileChanged, path, err)
+		}
+		return nil, fmt.Errorf("error when deleting file on github: %v", err)
+	}
+
+	return &FileResult{Path: path, Branch: branch, Action: FileDeleted, CommitSHA: contentResp.Commit.GetSHA()}, nil
+}
+
+// isFileExistsError reports whether the Contents API refused to create the
+// file because it was created meanwhile, github then asks for its SHA.
+// Other validation errors are reported with the same status.
+func isFileExistsError(res *github.Response, err error) bool {
+	return isValidationError(res, err, `"sha" wasn't supplied`, "already exists")
+}
+
+// isValidationError reports whether github rejected the request with 422
+// and one of the error messages contains one of the given texts.
+func isValidationError(res *github.Response, err error, texts ...string) bool {
+	if res == nil || res.StatusCode != http.StatusUnprocessableEntity {
+		return false
+	}
+	var errResp *github.ErrorResponse
+	if !errors.As(err, &errResp) {
+		return false
 	}
+	messages := []string{errResp.Message}
+	for _, e := range errResp.Errors {
+		messages = append(messages, e.Message)
+	}
+	for _, m := range messages {
+		for _, text := range texts {
+			if strings.Contains(strings.ToLower(m), strings.ToLower(text)) {
+				return true
+			}
+		}
+	}
+	return false
+}
 
-	return nil
+// isContentConflict reports whether the Contents API rejected the change
+// because the file SHA sent along is not the current one anymore.
+func isContentConflict(res *github.Response) bool {
+	return res != nil && res.StatusCode == http.StatusConflict
 }
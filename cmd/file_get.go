@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-github/v52/github"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+
+	fileGet.Flags().StringVar(&GithubOrgName, "githubOrgName", "", "name of the github organization")
+	fileGet.Flags().StringVar(&GithubRepo, "githubRepoName", "", "name of the repo to read the file from")
+	fileGet.Flags().StringVar(&FilePath, "filePath", "", "path to the file or directory, the repository root when empty")
+	fileGet.Flags().StringVar(&FileRef, "ref", "", "branch, tag or commit SHA to read from - defaults to the default branch")
+	fileGet.Flags().StringVar(&FileDest, "dest", "", "local path to save the file or directory to - prints to stdout when empty")
+	fileGet.Flags().BoolVar(&FileRecursive, "recursive", false, "include the whole subtree of a directory")
+	fileGet.Flags().BoolVar(&FileRaw, "raw", false, "print only the file content regardless of the output format")
+	fileGet.MarkFlagRequired("githubOrgName")
+	fileGet.MarkFlagRequired("githubRepoName")
+
+	fileGet.Run = func(cmd *cobra.Command, args []string) {
+		if err := GetFile(); err != nil {
+			log.Fatalf("error when getting a file from github repo: %v", err)
+		}
+	}
+}
+
+// RepoFile is a file or directory stored in a repository.
+type RepoFile struct {
+	Path    string `json:"path" yaml:"path"`
+	Type    string `json:"type" yaml:"type"`
+	Size    int    `json:"size" yaml:"size"`
+	SHA     string `json:"sha" yaml:"sha"`
+	Content string `json:"content,omitempty" yaml:"content,omitempty"`
+}
+
+func GetFile() error {
+
+	ctx := context.Background()
+
+	file, dir, _, err := GithubClient.Repositories.GetContents(ctx, GithubOrgName, GithubRepo, FilePath, &github.RepositoryContentGetOptions{Ref: FileRef})
+	if err != nil {
+		return fmt.Errorf("error when listing file contents: %v", err)
+	}
+
+	if file != nil {
+		content, err := fileContent(ctx, file)
+		if err != nil {
+			return err
+		}
+		if FileDest != "" {
+			dest := FileDest
+			if info, err := os.Stat(dest); err == nil && info.IsDir() {
+				dest = filepath.Join(dest, file.GetName())
+			}
+			return saveFile(dest, content, fileModeRegular)
+		}
+		if FileRaw || OutputFormat == OutputText || OutputFormat == "" {
+			_, err := os.Stdout.Write(content)
+			return err
+		}
+		return writeOutput(&RepoFile{Path: file.GetPath(), Type: file.GetType(), Size: file.GetSize(), SHA: file.GetSHA(), Content: string(content)}, nil)
+	}
+
+	if FileRecursive {
+		return getTree(ctx)
+	}
+
+	var files []*RepoFile
+	for _, f := range dir {
+		files = append(files, &RepoFile{Path: f.GetPath(), Type: f.GetType(), Size: f.GetSize(), SHA: f.GetSHA()})
+	}
+	if FileDest != "" {
+		return saveDir(ctx, dir)
+	}
+	return writeOutput(files, func() {
+		for _, f := range files {
+			fmt.Printf("%s\t%d\t%s\n", f.Type, f.Size, f.Path)
+		}
+	})
+}
+
+// saveDir downloads the files directly in the directory to the destination,
+// subdirectories are only downloaded with --recursive.
+func saveDir(ctx context.Context, dir []*github.RepositoryContent) error {
+	var saved, skipped int
+	for _, f := range dir {
+		if f.GetType() != "file" {
+			skipped++
+			continue
+		}
+		content, _, err := GithubClient.Git.GetBlobRaw(ctx, GithubOrgName, GithubRepo, f.GetSHA())
+		if err != nil {
+			return fmt.Errorf("error when downloading %s: %v", f.GetPath(), err)
+		}
+		if err := saveFile(filepath.Join(FileDest, f.GetName()), content, fileModeRegular); err != nil {
+			return err
+		}
+		saved++
+	}
+	log.Printf("downloaded %d files from %s to %s", saved, path.Join(GithubRepo, FilePath), FileDest)
+	if skipped > 0 {
+		log.Printf("skipped %d subdirectories and links, use --recursive to download the whole subtree", skipped)
+	}
+	return nil
+}
+
+// getTree lists or downloads the whole subtree under the file path,
+// using a single recursive tree request instead of listing every directory.
+func getTree(ctx context.Context) error {
+
+	ref := FileRef
+	if ref == "" {
+		var err error
+		if ref, err = resolveBranch(ctx); err != nil {
+			return err
+		}
+	}
+	tree, _, err := GithubClient.Git.GetTree(ctx, GithubOrgName, GithubRepo, ref, true)
+	if err != nil {
+		return fmt.Errorf("error when getting tree of %s: %v", ref, err)
+	}
+	if tree.GetTruncated() {
+		return fmt.Errorf("tree of %s is too large to be listed at once", ref)
+	}
+
+	prefix := strings.Trim(FilePath, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	var files []*RepoFile
+	for _, e := range tree.Entries {
+		if e.GetType() != "blob" || !strings.HasPrefix(e.GetPath(), prefix) {
+			continue
+		}
+		files = append(files, &RepoFile{Path: e.GetPath(), Type: "file", Size: e.GetSize(), SHA: e.GetSHA()})
+
+		if FileDest == "" {
+			continue
+		}
+		content, _, err := GithubClient.Git.GetBlobRaw(ctx, GithubOrgName, GithubRepo, e.GetSHA())
+		if err != nil {
+			return fmt.Errorf("error when downloading %s: %v", e.GetPath(), err)
+		}
+		rel := strings.TrimPrefix(e.GetPath(), prefix)
+		if err := saveFile(filepath.Join(FileDest, filepath.FromSlash(rel)), content, e.GetMode()); err != nil {
+			return err
+		}
+	}
+
+	if FileDest != "" {
+		log.Printf("downloaded %d files from %s to %s", len(files), path.Join(GithubRepo, prefix), FileDest)
+		return nil
+	}
+	return writeOutput(files, func() {
+		for _, f := range files {
+			fmt.Printf("%d\t%s\n", f.Size, f.Path)
+		}
+	})
+}
+
+// fileContent returns the decoded content of the file. Files larger than
+// 1MB are returned without content by the Contents API, so those are
+// downloaded as raw blobs.
+func fileContent(ctx context.Context, file *github.RepositoryContent) ([]byte, error) {
+	if file.GetEncoding() == "base64" {
+		content, err := file.GetContent()
+		if err != nil {
+			return nil, fmt.Errorf("error when decoding content of %s: %v", file.GetPath(), err)
+		}
+		return []byte(content), nil
+	}
+	content, _, err := GithubClient.Git.GetBlobRaw(ctx, GithubOrgName, GithubRepo, file.GetSHA())
+	if err != nil {
+		return nil, fmt.Errorf("error when downloading %s: %v", file.GetPath(), err)
+	}
+	return content, nil
+}
+
+func saveFile(dest string, content []byte, mode string) error {
+	perm := os.FileMode(0644)
+	if mode == fileModeExecutable {
+		perm = 0755
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return fmt.Errorf("error when creating directory for %s: %v", dest, err)
+	}
+	if err := os.WriteFile(dest, content, perm); err != nil {
+		return fmt.Errorf("error when saving %s: %v", dest, err)
+	}
+	return nil
+}
@@ -0,0 +1,737 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+
+	filePatch.Flags().StringVar(&GithubOrgName, "githubOrgName", "", "name of the github organization")
+	filePatch.Flags().StringVar(&GithubRepo, "githubRepoName", "", "name of the repo the file belongs to")
+	filePatch.Flags().StringVar(&FilePath, "filePath", "", "path to the YAML or JSON file that should be patched")
+	filePatch.Flags().StringVar(&GithubBranchName, "branchName", "", "the name of the branch to update - defaults to the default branch")
+	filePatch.Flags().StringArrayVar(&PatchSet, "set", nil, "set expression, e.g. '.spec.template.spec.containers[0].image=quay.io/org/app:v2', can be repeated. Strings stay strings unless the value is tagged, e.g. '!!int 5'")
+	filePatch.Flags().StringVar(&PatchMergeFile, "merge-patch", "", "path to a local JSON or YAML file with a JSON merge patch (RFC 7386)")
+	filePatch.Flags().IntVar(&PatchDocument, "document", 0, "index of the document to patch in a multi-document YAML file")
+	addCommitFlags(filePatch)
+	filePatch.MarkFlagRequired("githubOrgName")
+	filePatch.MarkFlagRequired("githubRepoName")
+	filePatch.MarkFlagRequired("filePath")
+
+	filePatch.Run = func(cmd *cobra.Command, args []string) {
+		if err := PatchFile(); err != nil {
+			log.Fatalf("error when patching a file in a github repo: %v", err)
+		}
+	}
+}
+
+// pathSegment is a single step of a set expression path,
+// either a mapping key or a sequence index.
+type pathSegment struct {
+	key   string
+	index int
+	isKey bool
+}
+
+func PatchFile() error {
+
+	ctx := context.Background()
+
+	if len(PatchSet) == 0 && PatchMergeFile == "" {
+		return fmt.Errorf("none of the parameters 'set' or 'merge-patch' specified")
+	}
+
+	var result *FileResult
+	err := withRetries(func() error {
+		// the patch is re-applied to the current content on every attempt
+		var err error
+		result, err = patchFile(ctx, FilePath)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return printFileResults([]*FileResult{result})
+}
+
+func patchFile(ctx context.Context, path string) (*FileResult, error) {
+	file, err := getFile(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if file == nil {
+		return nil, fmt.Errorf("file %s not found", path)
+	}
+	content, err := fileContent(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+
+	patched, err := patchContent(path, content)
+	if err != nil {
+		return nil, err
+	}
+
+	return writeFile(ctx, path, patched, file)
+}
+
+// patchContent applies the set expressions and the merge patch given via
+// flags to the file content. JSON files are recognized by their extension,
+// everything else is handled as YAML with comments preserved.
+func patchContent(filePath string, content []byte) ([]byte, error) {
+
+	docs, err := decodeYAMLDocuments(content)
+	if err != nil {
+		return nil, fmt.Errorf("error when parsing %s: %v", filePath, err)
+	}
+	if PatchDocument < 0 || PatchDocument >= len(docs) {
+		return nil, fmt.Errorf("document %d not found in %s, it has %d documents", PatchDocument, filePath, len(docs))
+	}
+	doc := docs[PatchDocument]
+	if len(doc.Content) == 0 {
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	root := doc.Content[0]
+
+	if PatchMergeFile != "" {
+		data, err := os.ReadFile(PatchMergeFile)
+		if err != nil {
+			return nil, fmt.Errorf("error when reading merge patch: %v", err)
+		}
+		var patch yaml.Node
+		if err := yaml.Unmarshal(data, &patch); err != nil {
+			return nil, fmt.Errorf("error when parsing merge patch %s: %v", PatchMergeFile, err)
+		}
+		if len(patch.Content) > 0 {
+			mergePatch(root, patch.Content[0])
+		}
+	}
+
+	for _, expr := range PatchSet {
+		p, v, ok := strings.Cut(expr, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid set expression '%s', expected 'path=value'", expr)
+		}
+		segments, err := parsePath(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid set expression '%s': %v", expr, err)
+		}
+		value, err := parseValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value in set expression '%s': %v", expr, err)
+		}
+		if err := setPath(root, segments, value); err != nil {
+			return nil, fmt.Errorf("error when applying '%s': %v", expr, err)
+		}
+	}
+
+	if strings.EqualFold(path.Ext(filePath), ".json") {
+		return encodeJSONNode(root, content)
+	}
+	return encodeYAMLDocuments(docs)
+}
+
+func decodeYAMLDocuments(content []byte) ([]*yaml.Node, error) {
+	var docs []*yaml.Node
+	dec := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		doc := &yaml.Node{}
+		if err := dec.Decode(doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	if len(docs) == 0 {
+		docs = append(docs, &yaml.Node{Kind: yaml.DocumentNode})
+	}
+	return docs, nil
+}
+
+func encodeYAMLDocuments(docs []*yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	for _, doc := range docs {
+		if err := enc.Encode(doc); err != nil {
+			return nil, err
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// parsePath parses paths like '.spec.containers[0].image' or
+// 'metadata.labels["app.kubernetes.io/name"]'.
+func parsePath(p string) ([]pathSegment, error) {
+	var segments []pathSegment
+	p = strings.TrimPrefix(strings.TrimSpace(p), ".")
+	for len(p) > 0 {
+		switch {
+		case p[0] == '.':
+			p = p[1:]
+		case strings.HasPrefix(p, `["`) || strings.HasPrefix(p, `['`):
+			quote := p[1]
+			end := strings.IndexByte(p[2:], quote)
+			if end < 0 || !strings.HasPrefix(p[2+end+1:], "]") {
+				return nil, fmt.Errorf("unterminated quoted key in '%s'", p)
+			}
+			segments = append(segments, pathSegment{key: p[2 : 2+end], isKey: true})
+			p = p[2+end+2:]
+		case p[0] == '[':
+			end := strings.IndexByte(p, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated index in '%s'", p)
+			}
+			index, err := strconv.Atoi(p[1:end])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("invalid index '%s'", p[1:end])
+			}
+			segments = append(segments, pathSegment{index: index})
+			p = p[end+1:]
+		default:
+			end := strings.IndexAny(p, ".[")
+			if end < 0 {
+				end = len(p)
+			}
+			segments = append(segments, pathSegment{key: p[:end], isKey: true})
+			p = p[end:]
+		}
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("empty path")
+	}
+	return segments, nil
+}
+
+// parseValue parses the value as YAML, so numbers and booleans keep their type
+// and structured values can be given in the flow style, e.g. '{a: 1}'.
+func parseValue(v string) (*yaml.Node, error) {
+	if v == "" {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str"}, nil
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(v), &doc); err != nil {
+		return nil, err
+	}
+	// values like '#foo' or only whitespace have no content in YAML
+	if len(doc.Content) == 0 {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}, nil
+	}
+	return doc.Content[0], nil
+}
+
+// setPath sets the value at the path, creating the missing mappings and
+// sequence items on the way.
+func setPath(node *yaml.Node, segments []pathSegment, value *yaml.Node) error {
+	for i, seg := range segments {
+		last := i == len(segments)-1
+		var child *yaml.Node
+
+		if seg.isKey {
+			if node.Kind != yaml.MappingNode {
+				if !isNullNode(node) {
+					return fmt.Errorf("cannot set key '%s' of a non-mapping value", seg.key)
+				}
+				*node = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			}
+			for j := 0; j < len(node.Content); j += 2 {
+				if node.Content[j].Value == seg.key {
+					child = node.Content[j+1]
+					break
+				}
+			}
+			if child == nil {
+				child = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: seg.key}, child)
+			}
+		} else {
+			if node.Kind != yaml.SequenceNode {
+				if !isNullNode(node) {
+					return fmt.Errorf("cannot set index %d of a non-sequence value", seg.index)
+				}
+				*node = yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			}
+			switch {
+			case seg.index < len(node.Content):
+				child = node.Content[seg.index]
+			case seg.index == len(node.Content):
+				child = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}
+				node.Content = append(node.Content, child)
+			default:
+				return fmt.Errorf("index %d out of range, the sequence has %d items", seg.index, len(node.Content))
+			}
+		}
+
+		if last {
+			replaceNode(child, keepStringType(child, value))
+			return nil
+		}
+		node = child
+	}
+	return nil
+}
+
+// keepStringType keeps a string a string when the new value would be resolved
+// to another type only because it is not quoted, e.g. a version '1.20' would
+// otherwise become a float. Quoted and explicitly tagged values are kept as they are.
+func keepStringType(old, value *yaml.Node) *yaml.Node {
+	if old.Kind != yaml.ScalarNode || old.Tag != "!!str" {
+		return value
+	}
+	if value.Kind != yaml.ScalarNode || value.Tag == "!!str" || value.Style&yaml.TaggedStyle != 0 {
+		return value
+	}
+	str := *value
+	str.Tag = "!!str"
+	return &str
+}
+
+// mergePatch applies the JSON merge patch to the target node as described in RFC 7386.
+func mergePatch(target, patch *yaml.Node) {
+	if patch.Kind != yaml.MappingNode {
+		replaceNode(target, patch)
+		return
+	}
+	if target.Kind != yaml.MappingNode {
+		replaceNode(target, &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"})
+	}
+
+	for i := 0; i < len(patch.Content); i += 2 {
+		key, value := patch.Content[i], patch.Content[i+1]
+		index := -1
+		for j := 0; j < len(target.Content); j += 2 {
+			if target.Content[j].Value == key.Value {
+				index = j
+				break
+			}
+		}
+
+		if isNullNode(value) {
+			if index >= 0 {
+				target.Content = append(target.Content[:index], target.Content[index+2:]...)
+			}
+			continue
+		}
+		if index < 0 {
+			child := &yaml.Node{}
+			mergePatch(child, value)
+			target.Content = append(target.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key.Value}, child)
+			continue
+		}
+		mergePatch(target.Content[index+1], value)
+	}
+}
+
+// replaceNode replaces the node in place, keeping its comments and the
+// quoting style of replaced strings.
+func replaceNode(dst, src *yaml.Node) {
+	old := *dst
+	*dst = *src
+	if old.Kind == yaml.ScalarNode && src.Kind == yaml.ScalarNode && src.Tag == "!!str" && src.Style == 0 {
+		dst.Style = old.Style &^ (yaml.LiteralStyle | yaml.FoldedStyle)
+	}
+	if dst.HeadComment == "" {
+		dst.HeadComment = old.HeadComment
+	}
+	if dst.LineComment == "" {
+		dst.LineComment = old.LineComment
+	}
+	if dst.FootComment == "" {
+		dst.FootComment = old.FootComment
+	}
+}
+
+func isNullNode(n *yaml.Node) bool {
+	return n.Kind == 0 || (n.Kind == yaml.ScalarNode && n.Tag == "!!null")
+}
+
+// encodeJSONNode encodes the node as JSON keeping the original content of the
+// values which did not change, so only the patched values are rewritten. New
+// values follow the indentation and separators of the surrounding content.
+func encodeJSONNode(node *yaml.Node, original []byte) ([]byte, error) {
+	w := &jsonPatchWriter{original: original, indent: jsonIndent(original)}
+	if !json.Valid(original) {
+		// e.g. an empty file, there is nothing to keep
+		if err := w.writeNew(node, "", true); err != nil {
+			return nil, err
+		}
+		if bytes.HasSuffix(original, []byte("\n")) {
+			w.buf.WriteByte('\n')
+		}
+		return w.buf.Bytes(), nil
+	}
+
+	p := &jsonSpanParser{data: original}
+	span, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	w.buf.Write(original[:span.start])
+	if err := w.write(node, span, ""); err != nil {
+		return nil, err
+	}
+	w.buf.Write(original[span.end:])
+	return w.buf.Bytes(), nil
+}
+
+// jsonIndent returns the indentation unit of the JSON content, taken from
+// the first indented line.
+func jsonIndent(content []byte) string {
+	for _, line := range strings.Split(string(content), "\n")[1:] {
+		if trimmed := strings.TrimLeft(line, " \t"); trimmed != "" {
+			if len(trimmed) < len(line) {
+				return line[:len(line)-len(trimmed)]
+			}
+			break
+		}
+	}
+	return "  "
+}
+
+// jsonSpan is the position of a value in the original JSON content. For
+// objects and arrays it holds the spans of the member values and items.
+type jsonSpan struct {
+	start, end int
+	// kind is '{', '[' or 0 for scalars
+	kind byte
+	// keys, keyStarts and keyEnds describe the member keys of objects
+	keys      []string
+	keyStarts []int
+	keyEnds   []int
+	items     []*jsonSpan
+}
+
+// jsonSpanParser records the spans of valid JSON content.
+type jsonSpanParser struct {
+	data []byte
+	pos  int
+}
+
+func (p *jsonSpanParser) skipSpace() {
+	for p.pos < len(p.data) && strings.IndexByte(" \t\r\n", p.data[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+func (p *jsonSpanParser) value() (*jsonSpan, error) {
+	p.skipSpace()
+	if p.pos >= len(p.data) {
+		return nil, fmt.Errorf("unexpected end of JSON content")
+	}
+	span := &jsonSpan{start: p.pos}
+	switch p.data[p.pos] {
+	case '{':
+		span.kind = '{'
+		p.pos++
+		for p.skipSpace(); p.data[p.pos] != '}'; p.skipSpace() {
+			if p.data[p.pos] == ',' {
+				p.pos++
+				p.skipSpace()
+			}
+			keyStart := p.pos
+			p.skipString()
+			var key string
+			if err := json.Unmarshal(p.data[keyStart:p.pos], &key); err != nil {
+				return nil, err
+			}
+			span.keys = append(span.keys, key)
+			span.keyStarts = append(span.keyStarts, keyStart)
+			span.keyEnds = append(span.keyEnds, p.pos)
+			p.skipSpace()
+			// the colon
+			p.pos++
+			item, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			span.items = append(span.items, item)
+		}
+		p.pos++
+	case '[':
+		span.kind = '['
+		p.pos++
+		for p.skipSpace(); p.data[p.pos] != ']'; p.skipSpace() {
+			if p.data[p.pos] == ',' {
+				p.pos++
+			}
+			item, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			span.items = append(span.items, item)
+		}
+		p.pos++
+	case '"':
+		p.skipString()
+	default:
+		for p.pos < len(p.data) && strings.IndexByte(",]} \t\r\n", p.data[p.pos]) < 0 {
+			p.pos++
+		}
+	}
+	span.end = p.pos
+	return span, nil
+}
+
+func (p *jsonSpanParser) skipString() {
+	for p.pos++; p.pos < len(p.data) && p.data[p.pos] != '"'; p.pos++ {
+		if p.data[p.pos] == '\\' {
+			p.pos++
+		}
+	}
+	p.pos++
+}
+
+// jsonPatchWriter writes the patched node, copying the spans of the original
+// content which did not change.
+type jsonPatchWriter struct {
+	original []byte
+	indent   string
+	buf      bytes.Buffer
+}
+
+// write writes the node in place of the original span, prefix is the
+// indentation of the line the value starts on.
+func (w *jsonPatchWriter) write(n *yaml.Node, span *jsonSpan, prefix string) error {
+	if n.Kind == yaml.AliasNode {
+		return w.write(n.Alias, span, prefix)
+	}
+	unchanged, err := w.unchanged(n, span)
+	if err != nil {
+		return err
+	}
+	switch {
+	case unchanged:
+		w.buf.Write(w.original[span.start:span.end])
+		return nil
+	case n.Kind == yaml.MappingNode && span.kind == '{' && len(span.items) > 0 && len(n.Content) > 0:
+		return w.writeObject(n, span, prefix)
+	case n.Kind == yaml.SequenceNode && span.kind == '[' && len(span.items) > 0 && len(n.Content) > 0:
+		return w.writeArray(n, span, prefix)
+	}
+	return w.writeNew(n, prefix, bytes.ContainsRune(w.original[span.start:span.end], '\n'))
+}
+
+// writeObject writes the members with the separators of the original object.
+func (w *jsonPatchWriter) writeObject(n *yaml.Node, span *jsonSpan, prefix string) error {
+	last := len(span.items) - 1
+	open := w.original[span.start+1 : span.keyStarts[0]]
+	colon := w.original[span.keyEnds[0]:span.items[0].start]
+	sep := w.separator(span, open, colon)
+	multiline, itemPrefix := linePrefix(open, prefix)
+
+	w.buf.WriteByte('{')
+	w.buf.Write(open)
+	used := make([]bool, len(span.keys))
+	for i := 0; i < len(n.Content); i += 2 {
+		key, value := n.Content[i].Value, n.Content[i+1]
+		j := 0
+		for j < len(span.keys) && (used[j] || span.keys[j] != key) {
+			j++
+		}
+		if j == len(span.keys) {
+			if i > 0 {
+				w.buf.Write(sep)
+			}
+			data, err := marshalJSON(key)
+			if err != nil {
+				return err
+			}
+			w.buf.Write(data)
+			w.buf.Write(colon)
+			if err := w.writeNew(value, itemPrefix, multiline); err != nil {
+				return err
+			}
+			continue
+		}
+		used[j] = true
+		switch {
+		case i > 0 && j > 0:
+			w.buf.Write(w.original[span.items[j-1].end:span.keyStarts[j]])
+		case i > 0:
+			w.buf.Write(sep)
+		}
+		w.buf.Write(w.original[span.keyStarts[j]:span.items[j].start])
+		if err := w.write(value, span.items[j], itemPrefix); err != nil {
+			return err
+		}
+	}
+	w.buf.Write(w.original[span.items[last].end:span.end])
+	return nil
+}
+
+// writeArray writes the items with the separators of the original array.
+func (w *jsonPatchWriter) writeArray(n *yaml.Node, span *jsonSpan, prefix string) error {
+	last := len(span.items) - 1
+	open := w.original[span.start+1 : span.items[0].start]
+	sep := w.separator(span, open, nil)
+	multiline, itemPrefix := linePrefix(open, prefix)
+
+	w.buf.WriteByte('[')
+	w.buf.Write(open)
+	for i, item := range n.Content {
+		if i > last {
+			w.buf.Write(sep)
+			if err := w.writeNew(item, itemPrefix, multiline); err != nil {
+				return err
+			}
+			continue
+		}
+		if i > 0 {
+			w.buf.Write(w.original[span.items[i-1].end:span.items[i].start])
+		}
+		if err := w.write(item, span.items[i], itemPrefix); err != nil {
+			return err
+		}
+	}
+	w.buf.Write(w.original[span.items[last].end:span.end])
+	return nil
+}
+
+// separator returns the separator written before new members and items, the
+// last one of the original content, or one derived from the text after the
+// opening bracket and the colon when there is only one.
+func (w *jsonPatchWriter) separator(span *jsonSpan, open, colon []byte) []byte {
+	last := len(span.items) - 1
+	switch {
+	case last > 0 && span.kind == '{':
+		return w.original[span.items[last-1].end:span.keyStarts[last]]
+	case last > 0:
+		return w.original[span.items[last-1].end:span.items[last].start]
+	case len(open) > 0:
+		return append([]byte{','}, open...)
+	case bytes.HasSuffix(colon, []byte(" ")) || span.kind == '[':
+		return []byte(", ")
+	}
+	return []byte{','}
+}
+
+// linePrefix returns whether the container puts its items on separate lines
+// and the indentation of these lines, taken from the text before the first item.
+func linePrefix(open []byte, prefix string) (bool, string) {
+	i := bytes.LastIndexByte(open, '\n')
+	if i < 0 {
+		return false, prefix
+	}
+	return true, string(open[i+1:])
+}
+
+// writeNew writes a value which has no original content, indented like the
+// surrounding content when it is spread over multiple lines.
+func (w *jsonPatchWriter) writeNew(n *yaml.Node, prefix string, multiline bool) error {
+	var compact bytes.Buffer
+	if err := writeJSONNode(&compact, n); err != nil {
+		return err
+	}
+	if !multiline {
+		w.buf.Write(compact.Bytes())
+		return nil
+	}
+	return json.Indent(&w.buf, compact.Bytes(), prefix, w.indent)
+}
+
+// unchanged reports whether the node has the same value as the original span.
+func (w *jsonPatchWriter) unchanged(n *yaml.Node, span *jsonSpan) (bool, error) {
+	var buf bytes.Buffer
+	if err := writeJSONNode(&buf, n); err != nil {
+		return false, err
+	}
+	var patched, original interface{}
+	if err := decodeJSONNumbers(buf.Bytes(), &patched); err != nil {
+		return false, err
+	}
+	if err := decodeJSONNumbers(w.original[span.start:span.end], &original); err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(patched, original), nil
+}
+
+// decodeJSONNumbers decodes the JSON keeping numbers as they are written.
+func decodeJSONNumbers(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+func writeJSONNode(buf *bytes.Buffer, n *yaml.Node) error {
+	switch n.Kind {
+	case yaml.AliasNode:
+		return writeJSONNode(buf, n.Alias)
+	case yaml.MappingNode:
+		buf.WriteByte('{')
+		for i := 0; i < len(n.Content); i += 2 {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, _ := marshalJSON(n.Content[i].Value)
+			buf.Write(key)
+			buf.WriteByte(':')
+			if err := writeJSONNode(buf, n.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case yaml.SequenceNode:
+		buf.WriteByte('[')
+		for i, item := range n.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSONNode(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case yaml.ScalarNode:
+		// keep numbers as they are written, e.g. 1.0 instead of 1
+		if (n.Tag == "!!int" || n.Tag == "!!float") && json.Valid([]byte(n.Value)) {
+			buf.WriteString(n.Value)
+			return nil
+		}
+		// timestamps would be decoded to time.Time and change their format
+		var v interface{} = n.Value
+		if n.Tag != "!!str" && n.Tag != "!!timestamp" {
+			if err := n.Decode(&v); err != nil {
+				return err
+			}
+		}
+		data, err := marshalJSON(v)
+		if err != nil {
+			return fmt.Errorf("value '%s' cannot be represented in JSON: %v", n.Value, err)
+		}
+		buf.Write(data)
+	default:
+		buf.WriteString("null")
+	}
+	return nil
+}
+
+// marshalJSON encodes the value like json.Marshal but without escaping
+// '&', '<' and '>', so strings like URLs are written the way they were read.
+func marshalJSON(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
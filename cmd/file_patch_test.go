@@ -0,0 +1,338 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    []pathSegment
+		wantErr bool
+	}{
+		{path: ".a.b", want: []pathSegment{{key: "a", isKey: true}, {key: "b", isKey: true}}},
+		{path: "a.b", want: []pathSegment{{key: "a", isKey: true}, {key: "b", isKey: true}}},
+		{path: ".spec.containers[0].image", want: []pathSegment{{key: "spec", isKey: true}, {key: "containers", isKey: true}, {index: 0}, {key: "image", isKey: true}}},
+		{path: `.metadata.labels["app.kubernetes.io/name"]`, want: []pathSegment{{key: "metadata", isKey: true}, {key: "labels", isKey: true}, {key: "app.kubernetes.io/name", isKey: true}}},
+		{path: `.a['b.c'][2]`, want: []pathSegment{{key: "a", isKey: true}, {key: "b.c", isKey: true}, {index: 2}}},
+		{path: "[1][0]", want: []pathSegment{{index: 1}, {index: 0}}},
+		{path: "", wantErr: true},
+		{path: ".", wantErr: true},
+		{path: ".a[", wantErr: true},
+		{path: ".a[x]", wantErr: true},
+		{path: ".a[-1]", wantErr: true},
+		{path: `.a["b]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := parsePath(tt.path)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		value string
+		tag   string
+		want  string
+	}{
+		{value: "", tag: "!!str", want: ""},
+		{value: "#foo", tag: "!!str", want: "#foo"},
+		{value: "   ", tag: "!!str", want: "   "},
+		{value: "5", tag: "!!int", want: "5"},
+		{value: "true", tag: "!!bool", want: "true"},
+		{value: `"1.20"`, tag: "!!str", want: "1.20"},
+		{value: "quay.io/org/app:v2", tag: "!!str", want: "quay.io/org/app:v2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseValue(tt.value)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Tag != tt.tag || got.Value != tt.want {
+				t.Errorf("got %s %q, want %s %q", got.Tag, got.Value, tt.tag, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		path    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "replace keeps comments",
+			doc:   "# head\nimage: app:v1 # current\nreplicas: 1\n",
+			path:  ".image",
+			value: "app:v2",
+			want:  "# head\nimage: app:v2 # current\nreplicas: 1\n",
+		},
+		{
+			name:  "creates missing mappings",
+			doc:   "a: 1\n",
+			path:  ".b.c",
+			value: "x",
+			want:  "a: 1\nb:\n  c: x\n",
+		},
+		{
+			name:  "sets sequence item",
+			doc:   "items:\n  - a\n  - b\n",
+			path:  ".items[1]",
+			value: "c",
+			want:  "items:\n  - a\n  - c\n",
+		},
+		{
+			name:  "appends sequence item",
+			doc:   "items:\n  - a\n",
+			path:  ".items[1]",
+			value: "b",
+			want:  "items:\n  - a\n  - b\n",
+		},
+		{
+			name:    "index out of range",
+			doc:     "items:\n  - a\n",
+			path:    ".items[3]",
+			value:   "b",
+			wantErr: true,
+		},
+		{
+			name:    "key of a scalar",
+			doc:     "a: 1\n",
+			path:    ".a.b",
+			value:   "x",
+			wantErr: true,
+		},
+		{
+			name:  "string stays a string",
+			doc:   "image:\n  tag: \"1.10\"\n",
+			path:  ".image.tag",
+			value: "1.20",
+			want:  "image:\n  tag: \"1.20\"\n",
+		},
+		{
+			name:  "unquoted string stays a string",
+			doc:   "version: v1\n",
+			path:  ".version",
+			value: "2",
+			want:  "version: \"2\"\n",
+		},
+		{
+			name:  "tagged value changes the type",
+			doc:   "replicas: \"1\"\n",
+			path:  ".replicas",
+			value: "!!int 3",
+			want:  "replicas: !!int 3\n",
+		},
+		{
+			name:  "numbers stay numbers",
+			doc:   "replicas: 1\n",
+			path:  ".replicas",
+			value: "3",
+			want:  "replicas: 3\n",
+		},
+		{
+			name:  "structured value",
+			doc:   "a: 1\n",
+			path:  ".b",
+			value: "{c: 1}",
+			want:  "a: 1\nb: {c: 1}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs, err := decodeYAMLDocuments([]byte(tt.doc))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			segments, err := parsePath(tt.path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			value, err := parseValue(tt.value)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			err = setPath(docs[0].Content[0], segments, value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := encodeYAMLDocuments(docs)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	// the examples of RFC 7386 appendix A
+	tests := []struct {
+		target string
+		patch  string
+		want   string
+	}{
+		{target: `{"a":"b"}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{target: `{"a":"b"}`, patch: `{"b":"c"}`, want: `{"a":"b","b":"c"}`},
+		{target: `{"a":"b"}`, patch: `{"a":null}`, want: `{}`},
+		{target: `{"a":"b","b":"c"}`, patch: `{"a":null}`, want: `{"b":"c"}`},
+		{target: `{"a":["b"]}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{target: `{"a":"c"}`, patch: `{"a":["b"]}`, want: `{"a":["b"]}`},
+		{target: `{"a":{"b":"c"}}`, patch: `{"a":{"b":"d","c":null}}`, want: `{"a":{"b":"d"}}`},
+		{target: `{"a":[{"b":"c"}]}`, patch: `{"a":[1]}`, want: `{"a":[1]}`},
+		{target: `["a","b"]`, patch: `["c","d"]`, want: `["c","d"]`},
+		{target: `{"a":"b"}`, patch: `["c"]`, want: `["c"]`},
+		{target: `{"a":"foo"}`, patch: `"bar"`, want: `"bar"`},
+		{target: `{"e":null}`, patch: `{"a":1}`, want: `{"e":null,"a":1}`},
+		{target: `[1,2]`, patch: `{"a":"b","c":null}`, want: `{"a":"b"}`},
+		{target: `{}`, patch: `{"a":{"bb":{"ccc":null}}}`, want: `{"a":{"bb":{}}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target+" "+tt.patch, func(t *testing.T) {
+			var target, patch yaml.Node
+			if err := yaml.Unmarshal([]byte(tt.target), &target); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := yaml.Unmarshal([]byte(tt.patch), &patch); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			mergePatch(target.Content[0], patch.Content[0])
+
+			var got bytes.Buffer
+			if err := writeJSONNode(&got, target.Content[0]); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("got %s, want %s", got.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestPatchContentJSON(t *testing.T) {
+	defer func() { PatchSet, PatchMergeFile, PatchDocument = nil, "", 0 }()
+
+	original := "{\n    \"name\": \"app\",\n    \"version\": \"1.10\",\n    \"released\": \"2023-01-01\",\n    \"ratio\": 1.0,\n    \"tags\": [\"a\", \"b\"],\n    \"nested\": {\"z\": 1, \"a\": 2}\n}\n"
+	tests := []struct {
+		name  string
+		set   []string
+		merge string
+		want  string
+	}{
+		{
+			name: "unchanged round-trip",
+			want: original,
+		},
+		{
+			name: "set to the current value",
+			set:  []string{".version=1.10", ".ratio=1.0", ".tags[0]=a"},
+			want: original,
+		},
+		{
+			name: "set keeps the order, types and formatting",
+			set:  []string{".version=1.20", ".tags[2]=c", ".nested.m=true"},
+			want: "{\n    \"name\": \"app\",\n    \"version\": \"1.20\",\n    \"released\": \"2023-01-01\",\n    \"ratio\": 1.0,\n    \"tags\": [\"a\", \"b\", \"c\"],\n    \"nested\": {\"z\": 1, \"a\": 2, \"m\": true}\n}\n",
+		},
+		{
+			name: "new structured values are indented",
+			set:  []string{".image.tag=v2"},
+			want: "{\n    \"name\": \"app\",\n    \"version\": \"1.10\",\n    \"released\": \"2023-01-01\",\n    \"ratio\": 1.0,\n    \"tags\": [\"a\", \"b\"],\n    \"nested\": {\"z\": 1, \"a\": 2},\n    \"image\": {\n        \"tag\": \"v2\"\n    }\n}\n",
+		},
+		{
+			name:  "merge patch",
+			merge: `{"name": null, "nested": {"z": null}, "released": "2024-02-02"}`,
+			want:  "{\n    \"version\": \"1.10\",\n    \"released\": \"2024-02-02\",\n    \"ratio\": 1.0,\n    \"tags\": [\"a\", \"b\"],\n    \"nested\": {\"a\": 2}\n}\n",
+		},
+		{
+			name:  "merge patch removing all members",
+			merge: `{"name": null, "version": null, "released": null, "ratio": null, "tags": null, "nested": null}`,
+			want:  "{}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			PatchSet, PatchMergeFile = tt.set, ""
+			if tt.merge != "" {
+				PatchMergeFile = filepath.Join(t.TempDir(), "patch.json")
+				if err := os.WriteFile(PatchMergeFile, []byte(tt.merge), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := patchContent("config.json", []byte(original))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatchContentYAMLDocuments(t *testing.T) {
+	defer func() { PatchSet, PatchDocument = nil, 0 }()
+
+	PatchSet = []string{".metadata.name=#foo"}
+	PatchDocument = 1
+	got, err := patchContent("deploy.yaml", []byte("a: 1\n---\n# second\nmetadata:\n  name: old\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "a: 1\n---\n# second\nmetadata:\n  name: '#foo'\n"
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	PatchDocument = 2
+	if _, err := patchContent("deploy.yaml", []byte("a: 1\n---\nb: 2\n")); err == nil {
+		t.Error("expected an error for a missing document")
+	}
+}
+
+func TestPatchContentJSONKeepsHTMLCharacters(t *testing.T) {
+	defer func() { PatchSet = nil }()
+
+	PatchSet = []string{".image=app:v2"}
+	original := "{\n  \"image\": \"app:v1\",\n  \"url\": \"http://a?x=1&y=<2>\",\n  \"a&b\": \"<tag>\"\n}\n"
+	got, err := patchContent("config.json", []byte(original))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := strings.Replace(original, "app:v1", "app:v2", 1)
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
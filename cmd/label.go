@@ -0,0 +1,325 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/google/go-github/v52/github"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+
+	for _, c := range []*cobra.Command{labelAdd, labelRemove, labelSet} {
+		c.Flags().StringVar(&GithubOrgName, "githubOrgName", "", "name of the github organization")
+		c.Flags().StringVar(&GithubRepo, "githubRepoName", "", "name of the repository the PR or issue belongs to")
+		c.Flags().IntVar(&IssueNumber, "number", 0, "the number of the PR or issue")
+		c.Flags().StringSliceVar(&LabelNames, "labels", nil, "comma separated list of label names")
+		c.MarkFlagRequired("githubOrgName")
+		c.MarkFlagRequired("githubRepoName")
+		c.MarkFlagRequired("number")
+	}
+	labelAdd.MarkFlagRequired("labels")
+	labelRemove.MarkFlagRequired("labels")
+
+	labelAdd.Run = func(cmd *cobra.Command, args []string) {
+		if err := AddLabels(); err != nil {
+			log.Fatalf("error when adding labels: %v", err)
+		}
+	}
+	labelRemove.Run = func(cmd *cobra.Command, args []string) {
+		if err := RemoveLabels(); err != nil {
+			log.Fatalf("error when removing labels: %v", err)
+		}
+	}
+	labelSet.Run = func(cmd *cobra.Command, args []string) {
+		if err := SetLabels(); err != nil {
+			log.Fatalf("error when setting labels: %v", err)
+		}
+	}
+
+	labelSync.Flags().StringVar(&GithubOrgName, "githubOrgName", "", "name of the github organization")
+	labelSync.Flags().StringSliceVar(&GithubRepos, "githubRepoName", nil, "name of the repository to sync the labels to, can be repeated")
+	labelSync.Flags().StringVar(&LabelsFile, "from", "", "path to the YAML file with label definitions")
+	labelSync.Flags().BoolVar(&LabelsDelete, "delete", false, "delete labels not defined in the file")
+	labelSync.Flags().BoolVar(&DryRun, "dryRun", false, "only print the changes that would be made")
+	labelSync.MarkFlagRequired("githubOrgName")
+	labelSync.MarkFlagRequired("githubRepoName")
+	labelSync.MarkFlagRequired("from")
+
+	labelSync.Run = func(cmd *cobra.Command, args []string) {
+		if err := SyncLabels(); err != nil {
+			log.Fatalf("error when syncing labels: %v", err)
+		}
+	}
+
+	milestoneSet.Flags().StringVar(&GithubOrgName, "githubOrgName", "", "name of the github organization")
+	milestoneSet.Flags().StringVar(&GithubRepo, "githubRepoName", "", "name of the repository the PR or issue belongs to")
+	milestoneSet.Flags().IntVar(&IssueNumber, "number", 0, "the number of the PR or issue")
+	milestoneSet.Flags().StringVar(&MilestoneTitle, "title", "", "title of the milestone, the milestone is cleared when empty")
+	milestoneSet.MarkFlagRequired("githubOrgName")
+	milestoneSet.MarkFlagRequired("githubRepoName")
+	milestoneSet.MarkFlagRequired("number")
+
+	milestoneSet.Run = func(cmd *cobra.Command, args []string) {
+		if err := SetMilestone(); err != nil {
+			log.Fatalf("error when setting milestone: %v", err)
+		}
+	}
+}
+
+// LabelDefinition is a single label in the file given to label sync.
+// Aliases are previous names of the label, an existing label with one of
+// these names is renamed instead of creating a new one.
+type LabelDefinition struct {
+	Name        string   `yaml:"name"`
+	Color       string   `yaml:"color"`
+	Description string   `yaml:"description"`
+	Aliases     []string `yaml:"aliases"`
+}
+
+// LabelChange is a single change made by label sync.
+type LabelChange struct {
+	Repo   string `json:"repo" yaml:"repo"`
+	Label  string `json:"label" yaml:"label"`
+	Action string `json:"action" yaml:"action"`
+	From   string `json:"from,omitempty" yaml:"from,omitempty"`
+}
+
+func AddLabels() error {
+	labels, _, err := GithubClient.Issues.AddLabelsToIssue(context.Background(), GithubOrgName, GithubRepo, IssueNumber, LabelNames)
+	if err != nil {
+		return err
+	}
+	return printIssueLabels(labels)
+}
+
+func RemoveLabels() error {
+
+	ctx := context.Background()
+
+	for _, l := range LabelNames {
+		_, err := GithubClient.Issues.RemoveLabelForIssue(ctx, GithubOrgName, GithubRepo, IssueNumber, l)
+		if err != nil {
+			return fmt.Errorf("error when removing label %s: %v", l, err)
+		}
+	}
+
+	var labels []*github.Label
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		page, res, err := GithubClient.Issues.ListLabelsByIssue(ctx, GithubOrgName, GithubRepo, IssueNumber, opts)
+		if err != nil {
+			return err
+		}
+		labels = append(labels, page...)
+		if res.NextPage == 0 {
+			break
+		}
+		opts.Page = res.NextPage
+	}
+	return printIssueLabels(labels)
+}
+
+func SetLabels() error {
+	// an empty list removes all the labels
+	labels, _, err := GithubClient.Issues.ReplaceLabelsForIssue(context.Background(), GithubOrgName, GithubRepo, IssueNumber, append([]string{}, LabelNames...))
+	if err != nil {
+		return err
+	}
+	return printIssueLabels(labels)
+}
+
+func printIssueLabels(labels []*github.Label) error {
+	var names []string
+	for _, l := range labels {
+		names = append(names, l.GetName())
+	}
+	return writeOutput(names, func() {
+		fmt.Printf("labels of #%d: %s\n", IssueNumber, strings.Join(names, ", "))
+	})
+}
+
+func SyncLabels() error {
+
+	ctx := context.Background()
+
+	data, err := os.ReadFile(LabelsFile)
+	if err != nil {
+		return fmt.Errorf("error when reading labels file: %v", err)
+	}
+	var definitions []LabelDefinition
+	if err := yaml.Unmarshal(data, &definitions); err != nil {
+		return fmt.Errorf("error when parsing labels file %s: %v", LabelsFile, err)
+	}
+	for i, d := range definitions {
+		if d.Name == "" {
+			return fmt.Errorf("label #%d in %s has no name", i+1, LabelsFile)
+		}
+		definitions[i].Color = normalizeLabelColor(d.Color)
+	}
+
+	var changes []*LabelChange
+	for _, repo := range GithubRepos {
+		repoChanges, err := syncRepoLabels(ctx, repo, definitions)
+		changes = append(changes, repoChanges...)
+		if err != nil {
+			return fmt.Errorf("error when syncing labels of %s: %v", repo, err)
+		}
+	}
+
+	return writeOutput(changes, func() {
+		if len(changes) == 0 {
+			fmt.Println("labels already in sync")
+		}
+		for _, c := range changes {
+			if c.From != "" {
+				fmt.Printf("%s\t%s\t%s (from %s)\n", c.Repo, c.Action, c.Label, c.From)
+				continue
+			}
+			fmt.Printf("%s\t%s\t%s\n", c.Repo, c.Action, c.Label)
+		}
+	})
+}
+
+func syncRepoLabels(ctx context.Context, repo string, definitions []LabelDefinition) ([]*LabelChange, error) {
+
+	existing := map[string]*github.Label{}
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		labels, res, err := GithubClient.Issues.ListLabels(ctx, GithubOrgName, repo, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range labels {
+			existing[strings.ToLower(l.GetName())] = l
+		}
+		if res.NextPage == 0 {
+			break
+		}
+		opts.Page = res.NextPage
+	}
+
+	var changes []*LabelChange
+	defined := map[string]bool{}
+	for _, d := range definitions {
+		defined[strings.ToLower(d.Name)] = true
+		desired := &github.Label{Name: github.String(d.Name)}
+		if d.Color != "" {
+			desired.Color = github.String(d.Color)
+		}
+		if d.Description != "" {
+			desired.Description = github.String(d.Description)
+		}
+
+		current, ok := existing[strings.ToLower(d.Name)]
+		from := ""
+		if !ok {
+			for _, alias := range d.Aliases {
+				if current, ok = existing[strings.ToLower(alias)]; ok {
+					from = current.GetName()
+					defined[strings.ToLower(alias)] = true
+					break
+				}
+			}
+		}
+
+		change := &LabelChange{Repo: repo, Label: d.Name, From: from}
+		switch {
+		case !ok:
+			change.Action = "create"
+		case from != "":
+			change.Action = "rename"
+		case current.GetName() != d.Name ||
+			(d.Color != "" && normalizeLabelColor(current.GetColor()) != d.Color) ||
+			(d.Description != "" && current.GetDescription() != d.Description):
+			change.Action = "update"
+		default:
+			continue
+		}
+		changes = append(changes, change)
+		if DryRun {
+			continue
+		}
+
+		var err error
+		if change.Action == "create" {
+			_, _, err = GithubClient.Issues.CreateLabel(ctx, GithubOrgName, repo, desired)
+		} else {
+			_, _, err = GithubClient.Issues.EditLabel(ctx, GithubOrgName, repo, current.GetName(), desired)
+		}
+		if err != nil {
+			return changes, fmt.Errorf("error when trying to %s label %s: %v", change.Action, d.Name, err)
+		}
+	}
+
+	if !LabelsDelete {
+		return changes, nil
+	}
+	for key, l := range existing {
+		if defined[key] {
+			continue
+		}
+		changes = append(changes, &LabelChange{Repo: repo, Label: l.GetName(), Action: "delete"})
+		if DryRun {
+			continue
+		}
+		if _, err := GithubClient.Issues.DeleteLabel(ctx, GithubOrgName, repo, l.GetName()); err != nil {
+			return changes, fmt.Errorf("error when deleting label %s: %v", l.GetName(), err)
+		}
+	}
+
+	return changes, nil
+}
+
+// normalizeLabelColor strips the leading '#' as github expects a bare hex color.
+func normalizeLabelColor(color string) string {
+	return strings.ToLower(strings.TrimPrefix(color, "#"))
+}
+
+func SetMilestone() error {
+
+	ctx := context.Background()
+
+	if MilestoneTitle == "" {
+		_, _, err := GithubClient.Issues.RemoveMilestone(ctx, GithubOrgName, GithubRepo, IssueNumber)
+		if err != nil {
+			return err
+		}
+		log.Printf("milestone of #%d cleared", IssueNumber)
+		return nil
+	}
+
+	var milestone *github.Milestone
+	opts := &github.MilestoneListOptions{State: "all", ListOptions: github.ListOptions{PerPage: 100}}
+	for milestone == nil {
+		list, res, err := GithubClient.Issues.ListMilestones(ctx, GithubOrgName, GithubRepo, opts)
+		if err != nil {
+			return err
+		}
+		for _, m := range list {
+			if m.GetTitle() == MilestoneTitle {
+				milestone = m
+				break
+			}
+		}
+		if res.NextPage == 0 {
+			break
+		}
+		opts.Page = res.NextPage
+	}
+	if milestone == nil {
+		return fmt.Errorf("milestone '%s' not found in %s/%s", MilestoneTitle, GithubOrgName, GithubRepo)
+	}
+
+	_, _, err := GithubClient.Issues.Edit(ctx, GithubOrgName, GithubRepo, IssueNumber, &github.IssueRequest{Milestone: milestone.Number})
+	if err != nil {
+		return err
+	}
+	log.Printf("milestone of #%d set to '%s'", IssueNumber, MilestoneTitle)
+	return nil
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+	OutputYAML = "yaml"
+)
+
+// writeOutput prints v to stdout in the format selected via --output.
+// For the text format the given printer is called instead, so every command
+// can keep its own human readable layout.
+func writeOutput(v interface{}, text func()) error {
+	switch OutputFormat {
+	case OutputJSON:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case OutputYAML:
+		enc := yaml.NewEncoder(os.Stdout)
+		enc.SetIndent(2)
+		defer enc.Close()
+		return enc.Encode(v)
+	case OutputText, "":
+		text()
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format '%s', use one of: %s, %s, %s", OutputFormat, OutputText, OutputJSON, OutputYAML)
+	}
+}
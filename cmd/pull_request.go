@@ -2,7 +2,12 @@ package cmd
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"path"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/google/go-github/v52/github"
@@ -47,6 +52,150 @@ func init() {
 			log.Fatalf("error when merging PR: %v", err)
 		}
 	}
+
+	prWaitComment.Flags().StringVar(&GithubOrgName, "githubOrgName", "", "name of the github organization")
+	prWaitComment.Flags().StringVar(&GithubRepo, "githubRepoName", "", "name of the repository the PR belongs to")
+	prWaitComment.Flags().StringVar(&GithubBranchName, "branchName", "", "the name of the PR head branch, used when prNumber is not specified")
+	prWaitComment.Flags().IntVar(&GithubPRNumber, "prNumber", 0, "the number of the PR")
+	prWaitComment.Flags().StringVar(&CommentPattern, "pattern", "", "regex the comment body has to match")
+	prWaitComment.Flags().StringVar(&CommentAuthor, "author", "", "login of the comment author, e.g. renovate[bot] - optional")
+	prWaitComment.Flags().DurationVar(&CommentSince, "since", 10*time.Minute, "how far back to look for comments created before the command started")
+	prWaitComment.Flags().DurationVar(&WaitTimeout, "timeout", 10*time.Minute, "how long to wait")
+	prWaitComment.Flags().DurationVar(&WaitPollingInterval, "interval", 10*time.Second, "how often to poll")
+	prWaitComment.MarkFlagRequired("githubOrgName")
+	prWaitComment.MarkFlagRequired("githubRepoName")
+	prWaitComment.MarkFlagRequired("pattern")
+
+	prWaitComment.Run = func(cmd *cobra.Command, args []string) {
+		if err := WaitPRComment(); err != nil {
+			log.Fatalf("error when waiting for PR comment: %v", err)
+		}
+	}
+
+	prList.Flags().StringVar(&GithubOrgName, "githubOrgName", "", "name of the github organization")
+	prList.Flags().StringVar(&GithubRepo, "githubRepoName", "", "name of the repository to list PRs from, the whole organization is searched when not specified")
+	prList.Flags().StringVar(&PRState, "state", "open", "state of the PRs, one of: open, closed, merged, all")
+	prList.Flags().StringVar(&PRBase, "base", "", "the name of the PR base branch")
+	prList.Flags().StringVar(&PRHead, "head", "", "the name of the PR head branch")
+	prList.Flags().StringVar(&PRAuthor, "author", "", "login of the PR author")
+	prList.Flags().StringSliceVar(&PRLabels, "label", nil, "label the PRs have to have, can be repeated")
+	prList.Flags().StringVar(&PRDraft, "draft", "", "'true' to list only draft PRs, 'false' to list only ready PRs")
+	prList.Flags().StringVar(&PRCreated, "created", "", "creation date range, e.g. '>=2023-05-01' or '2023-05-01..2023-05-31'")
+	prList.Flags().StringVar(&PRUpdated, "updated", "", "last update date range, e.g. '<2023-05-01'")
+	prList.Flags().StringSliceVar(&PRSearchFilter, "query", nil, "additional search qualifiers, e.g. 'review:approved', can be repeated")
+	prList.Flags().BoolVar(&PRDetails, "details", false, "also fetch the head, base, mergeable state and check status of every PR, costs 3 API calls per PR")
+	prList.MarkFlagRequired("githubOrgName")
+
+	prList.Run = func(cmd *cobra.Command, args []string) {
+		if err := ListPRs(); err != nil {
+			log.Fatalf("error when listing PRs: %v", err)
+		}
+	}
+
+	prPrune.Flags().StringVar(&GithubOrgName, "githubOrgName", "", "name of the github organization")
+	prPrune.Flags().StringVar(&GithubRepo, "githubRepoName", "", "name of the repository to prune PRs in")
+	prPrune.Flags().StringVar(&PRHeadRegex, "headRegex", "", "regex to match for the head branches of the PRs about to be closed")
+	prPrune.Flags().StringVar(&PRAuthor, "author", "", "login of the PR author")
+	prPrune.Flags().StringSliceVar(&PRLabels, "label", nil, "label the PRs have to have, can be repeated")
+	prPrune.Flags().DurationVar(&PROlderThan, "olderThan", 0, "close only PRs created before this duration, e.g. 48h")
+	prPrune.Flags().StringVar(&PRCloseComment, "comment", "", "comment to add to the PRs before closing them - optional")
+	prPrune.Flags().BoolVar(&DryRun, "dryRun", false, "only print the PRs that would be closed")
+	prPrune.MarkFlagRequired("githubOrgName")
+	prPrune.MarkFlagRequired("githubRepoName")
+
+	prPrune.Run = func(cmd *cobra.Command, args []string) {
+		if err := PrunePRs(); err != nil {
+			log.Fatalf("error when pruning PRs: %v", err)
+		}
+	}
+
+	prUpdateBranch.Flags().StringVar(&GithubOrgName, "githubOrgName", "", "name of the github organization")
+	prUpdateBranch.Flags().StringVar(&GithubRepo, "githubRepoName", "", "name of the repository the PR belongs to")
+	prUpdateBranch.Flags().StringVar(&GithubBranchName, "branchName", "", "the name of the PR head branch, used when prNumber is not specified")
+	prUpdateBranch.Flags().IntVar(&GithubPRNumber, "prNumber", 0, "the number of the PR")
+	prUpdateBranch.Flags().StringVar(&PRExpectedSHA, "expectedHeadSHA", "", "the expected SHA of the PR head, the update fails if the head moved - defaults to the current head")
+	prUpdateBranch.Flags().BoolVar(&PRWaitChecks, "waitChecks", false, "wait until the checks on the new head complete successfully")
+	prUpdateBranch.Flags().DurationVar(&WaitTimeout, "timeout", 10*time.Minute, "how long to wait")
+	prUpdateBranch.Flags().DurationVar(&WaitPollingInterval, "interval", 10*time.Second, "how often to poll")
+	prUpdateBranch.MarkFlagRequired("githubOrgName")
+	prUpdateBranch.MarkFlagRequired("githubRepoName")
+
+	prUpdateBranch.Run = func(cmd *cobra.Command, args []string) {
+		if err := UpdatePRBranch(); err != nil {
+			log.Fatalf("error when updating PR branch: %v", err)
+		}
+	}
+}
+
+const (
+	CheckStatusSuccess = "success"
+	CheckStatusFailure = "failure"
+	CheckStatusPending = "pending"
+	CheckStatusNone    = "none"
+)
+
+// PRInfo is a summary of a single PR as printed by pr-list.
+type PRInfo struct {
+	Number         int       `json:"number" yaml:"number"`
+	Repo           string    `json:"repo" yaml:"repo"`
+	Title          string    `json:"title" yaml:"title"`
+	Author         string    `json:"author" yaml:"author"`
+	State          string    `json:"state" yaml:"state"`
+	Draft          bool      `json:"draft,omitempty" yaml:"draft,omitempty"`
+	Head           string    `json:"head,omitempty" yaml:"head,omitempty"`
+	HeadSHA        string    `json:"headSha,omitempty" yaml:"headSha,omitempty"`
+	Base           string    `json:"base,omitempty" yaml:"base,omitempty"`
+	MergeableState string    `json:"mergeableState,omitempty" yaml:"mergeableState,omitempty"`
+	CheckStatus    string    `json:"checkStatus,omitempty" yaml:"checkStatus,omitempty"`
+	CreatedAt      time.Time `json:"createdAt" yaml:"createdAt"`
+	Age            string    `json:"age" yaml:"age"`
+	URL            string    `json:"url" yaml:"url"`
+}
+
+// PRComment is a PR comment matching the pattern given to pr-wait-comment,
+// together with the values of the regex capture groups.
+type PRComment struct {
+	ID          int64             `json:"id" yaml:"id"`
+	PRNumber    int               `json:"prNumber" yaml:"prNumber"`
+	Author      string            `json:"author" yaml:"author"`
+	CreatedAt   time.Time         `json:"createdAt" yaml:"createdAt"`
+	URL         string            `json:"url" yaml:"url"`
+	Body        string            `json:"body" yaml:"body"`
+	Groups      []string          `json:"groups,omitempty" yaml:"groups,omitempty"`
+	NamedGroups map[string]string `json:"namedGroups,omitempty" yaml:"namedGroups,omitempty"`
+}
+
+// getPRNumber returns the PR number given via --prNumber, or looks up
+// the open PR created from the branch given via --branchName.
+func getPRNumber(ctx context.Context) (int, error) {
+	if GithubPRNumber != 0 {
+		return GithubPRNumber, nil
+	}
+	if GithubBranchName == "" {
+		return 0, fmt.Errorf("none of the parameters 'prNumber' or 'branchName' specified")
+	}
+
+	opts := &github.PullRequestListOptions{
+		Head:        fmt.Sprintf("%s:%s", GithubOrgName, GithubBranchName),
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	for {
+		list, res, err := GithubClient.PullRequests.List(ctx, GithubOrgName, GithubRepo, opts)
+		if err != nil {
+			return 0, err
+		}
+		for _, pr := range list {
+			if pr.Head.GetRef() == GithubBranchName {
+				return pr.GetNumber(), nil
+			}
+		}
+		if res.NextPage == 0 {
+			break
+		}
+		opts.Page = res.NextPage
+	}
+
+	return 0, fmt.Errorf("no open pr found for branch %s in %s/%s", GithubBranchName, GithubOrgName, GithubRepo)
 }
 
 func GetPR() error {
@@ -118,3 +267,560 @@ func CommentPR() error {
 
 	return nil
 }
+
+func WaitPRComment() error {
+
+	ctx := context.Background()
+
+	re, err := regexp.Compile(CommentPattern)
+	if err != nil {
+		return fmt.Errorf("problem with regexp: %+v", err)
+	}
+
+	prNumber, err := getPRNumber(ctx)
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(WaitTimeout)
+	since := time.Now().Add(-CommentSince)
+	// the since filter is inclusive, so remember which comment revisions were already checked
+	checked := map[int64]time.Time{}
+
+	log.Printf("waiting for a comment matching '%s' on pr %d in %s/%s", CommentPattern, prNumber, GithubOrgName, GithubRepo)
+	for {
+		opts := &github.IssueListCommentsOptions{Since: &since, ListOptions: github.ListOptions{PerPage: 100}}
+		nextSince := since
+		for {
+			comments, res, err := GithubClient.Issues.ListComments(ctx, GithubOrgName, GithubRepo, prNumber, opts)
+			if err != nil {
+				return err
+			}
+
+			for _, c := range comments {
+				updatedAt := c.GetUpdatedAt().Time
+				if updatedAt.After(nextSince) {
+					nextSince = updatedAt
+				}
+				if t, ok := checked[c.GetID()]; ok && t.Equal(updatedAt) {
+					continue
+				}
+				checked[c.GetID()] = updatedAt
+
+				if CommentAuthor != "" && !strings.EqualFold(c.GetUser().GetLogin(), CommentAuthor) {
+					continue
+				}
+				match := re.FindStringSubmatch(c.GetBody())
+				if match == nil {
+					continue
+				}
+				return printPRComment(newPRComment(prNumber, c, re, match))
+			}
+
+			if res.NextPage == 0 {
+				break
+			}
+			opts.Page = res.NextPage
+		}
+		since = nextSince
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out after %s waiting for a comment matching '%s' on pr %d", WaitTimeout, CommentPattern, prNumber)
+		}
+		time.Sleep(WaitPollingInterval)
+	}
+}
+
+func newPRComment(prNumber int, c *github.IssueComment, re *regexp.Regexp, match []string) *PRComment {
+	comment := &PRComment{
+		ID:        c.GetID(),
+		PRNumber:  prNumber,
+		Author:    c.GetUser().GetLogin(),
+		CreatedAt: c.GetCreatedAt().Time,
+		URL:       c.GetHTMLURL(),
+		Body:      c.GetBody(),
+		Groups:    match[1:],
+	}
+	for i, name := range re.SubexpNames() {
+		if i == 0 || name == "" {
+			continue
+		}
+		if comment.NamedGroups == nil {
+			comment.NamedGroups = map[string]string{}
+		}
+		comment.NamedGroups[name] = match[i]
+	}
+	return comment
+}
+
+func printPRComment(c *PRComment) error {
+	return writeOutput(c, func() {
+		fmt.Printf("comment %d by %s on pr %d: %s\n", c.ID, c.Author, c.PRNumber, c.URL)
+		fmt.Println(c.Body)
+		for i, g := range c.Groups {
+			fmt.Printf("group %d: %s\n", i+1, g)
+		}
+		for name, g := range c.NamedGroups {
+			fmt.Printf("group %s: %s\n", name, g)
+		}
+	})
+}
+
+// prSearchQuery builds the Search API query out of the pr-list flags.
+func prSearchQuery() (string, error) {
+	q := []string{"is:pr"}
+	if GithubRepo != "" {
+		q = append(q, fmt.Sprintf("repo:%s/%s", GithubOrgName, GithubRepo))
+	} else {
+		q = append(q, fmt.Sprintf("org:%s", GithubOrgName))
+	}
+
+	switch PRState {
+	case "open", "closed":
+		q = append(q, "is:"+PRState)
+	case "merged":
+		q = append(q, "is:merged")
+	case "all", "":
+	default:
+		return "", fmt.Errorf("unsupported PR state '%s'", PRState)
+	}
+
+	switch PRDraft {
+	case "true", "false":
+		q = append(q, "draft:"+PRDraft)
+	case "":
+	default:
+		return "", fmt.Errorf("unsupported value '%s' of the 'draft' parameter", PRDraft)
+	}
+
+	if PRBase != "" {
+		q = append(q, "base:"+PRBase)
+	}
+	if PRHead != "" {
+		q = append(q, "head:"+PRHead)
+	}
+	if PRAuthor != "" {
+		q = append(q, "author:"+PRAuthor)
+	}
+	for _, l := range PRLabels {
+		q = append(q, fmt.Sprintf("label:%q", l))
+	}
+	if PRCreated != "" {
+		q = append(q, "created:"+PRCreated)
+	}
+	if PRUpdated != "" {
+		q = append(q, "updated:"+PRUpdated)
+	}
+	q = append(q, PRSearchFilter...)
+
+	return strings.Join(q, " "), nil
+}
+
+// searchResultLimit is the maximum number of results the search API returns for a query.
+const searchResultLimit = 1000
+
+func ListPRs() error {
+
+	ctx := context.Background()
+
+	query, err := prSearchQuery()
+	if err != nil {
+		return err
+	}
+	log.Printf("searching PRs: %s", query)
+
+	var issues []*github.Issue
+	opts := &github.SearchOptions{Sort: "created", Order: "desc", ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		result, res, err := GithubClient.Search.Issues(ctx, query, opts)
+		if err != nil {
+			return fmt.Errorf("error when searching PRs: %v", err)
+		}
+		issues = append(issues, result.Issues...)
+		// the search API refuses to go past the first 1000 results
+		if res.NextPage == 0 || len(issues) >= searchResultLimit {
+			if result.GetTotal() > len(issues) {
+				log.Printf("the search API returns only the first %d of %d matching PRs, narrow down the query to see the rest", len(issues), result.GetTotal())
+			}
+			break
+		}
+		opts.Page = res.NextPage
+	}
+
+	var prs []*PRInfo
+	for _, issue := range issues {
+		repo := GithubRepo
+		if repo == "" {
+			repo = issue.GetRepository().GetName()
+			if repo == "" {
+				// search results only carry the repository URL
+				repo = path.Base(issue.GetRepositoryURL())
+			}
+		}
+		if !PRDetails {
+			prs = append(prs, newPRInfoFromIssue(repo, issue))
+			continue
+		}
+		pr, _, err := GithubClient.PullRequests.Get(ctx, GithubOrgName, repo, issue.GetNumber())
+		if err != nil {
+			return fmt.Errorf("error when getting pr %d from %s: %v", issue.GetNumber(), repo, err)
+		}
+		checkStatus, err := getCheckStatus(ctx, repo, pr.GetHead().GetSHA())
+		if err != nil {
+			return err
+		}
+		prs = append(prs, newPRInfo(repo, pr, checkStatus))
+	}
+
+	return writeOutput(prs, func() {
+		for _, pr := range prs {
+			if !PRDetails {
+				fmt.Printf("%s#%d\t%s\t%s\t%s\t%s\n", pr.Repo, pr.Number, pr.State, pr.Author, pr.Age, pr.Title)
+				continue
+			}
+			fmt.Printf("%s#%d\t%s\t%s\t%s\t%s\t%s\n", pr.Repo, pr.Number, pr.Head, pr.MergeableState, pr.CheckStatus, pr.Age, pr.Title)
+		}
+	})
+}
+
+// newPRInfoFromIssue fills the PR info from the search result alone, the
+// fields which need the PR itself are left empty.
+func newPRInfoFromIssue(repo string, issue *github.Issue) *PRInfo {
+	return &PRInfo{
+		Number:    issue.GetNumber(),
+		Repo:      repo,
+		Title:     issue.GetTitle(),
+		Author:    issue.GetUser().GetLogin(),
+		State:     issue.GetState(),
+		CreatedAt: issue.GetCreatedAt().Time,
+		Age:       formatAge(time.Since(issue.GetCreatedAt().Time)),
+		URL:       issue.GetHTMLURL(),
+	}
+}
+
+func newPRInfo(repo string, pr *github.PullRequest, checkStatus string) *PRInfo {
+	state := pr.GetState()
+	if pr.GetMerged() {
+		state = "merged"
+	}
+	return &PRInfo{
+		Number:         pr.GetNumber(),
+		Repo:           repo,
+		Title:          pr.GetTitle(),
+		Author:         pr.GetUser().GetLogin(),
+		State:          state,
+		Draft:          pr.GetDraft(),
+		Head:           pr.GetHead().GetRef(),
+		HeadSHA:        pr.GetHead().GetSHA(),
+		Base:           pr.GetBase().GetRef(),
+		MergeableState: pr.GetMergeableState(),
+		CheckStatus:    checkStatus,
+		CreatedAt:      pr.GetCreatedAt().Time,
+		Age:            formatAge(time.Since(pr.GetCreatedAt().Time)),
+		URL:            pr.GetHTMLURL(),
+	}
+}
+
+// getCheckStatus summarizes both the check runs and the commit statuses
+// reported for the given commit into a single status.
+func getCheckStatus(ctx context.Context, repo, sha string) (string, error) {
+	var states []string
+
+	opts := &github.ListCheckRunsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		list, res, err := GithubClient.Checks.ListCheckRunsForRef(ctx, GithubOrgName, repo, sha, opts)
+		if err != nil {
+			return "", fmt.Errorf("error when listing check runs for %s: %v", sha, err)
+		}
+		for _, check := range list.CheckRuns {
+			if check.GetStatus() != "completed" {
+				states = append(states, CheckStatusPending)
+				continue
+			}
+			switch check.GetConclusion() {
+			case "success", "neutral", "skipped":
+				states = append(states, CheckStatusSuccess)
+			default:
+				states = append(states, CheckStatusFailure)
+			}
+		}
+		if res.NextPage == 0 {
+			break
+		}
+		opts.Page = res.NextPage
+	}
+
+	combined, _, err := GithubClient.Repositories.GetCombinedStatus(ctx, GithubOrgName, repo, sha, &github.ListOptions{PerPage: 100})
+	if err != nil {
+		return "", fmt.Errorf("error when getting commit status for %s: %v", sha, err)
+	}
+	if combined.GetTotalCount() > 0 {
+		switch combined.GetState() {
+		case "success":
+			states = append(states, CheckStatusSuccess)
+		case "pending":
+			states = append(states, CheckStatusPending)
+		default:
+			states = append(states, CheckStatusFailure)
+		}
+	}
+
+	status := CheckStatusNone
+	for _, s := range states {
+		switch {
+		case s == CheckStatusFailure:
+			return CheckStatusFailure, nil
+		case s == CheckStatusPending:
+			status = CheckStatusPending
+		case status == CheckStatusNone:
+			status = CheckStatusSuccess
+		}
+	}
+	return status, nil
+}
+
+// formatAge formats a duration as days and hours, e.g. "3d4h".
+func formatAge(d time.Duration) string {
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	if days == 0 {
+		return fmt.Sprintf("%dh%dm", hours, int(d.Minutes())%60)
+	}
+	return fmt.Sprintf("%dd%dh", days, hours)
+}
+
+// PRPruneResult describes what pr-prune did with a single PR.
+type PRPruneResult struct {
+	Number        int    `json:"number" yaml:"number"`
+	Title         string `json:"title" yaml:"title"`
+	Head          string `json:"head" yaml:"head"`
+	Age           string `json:"age" yaml:"age"`
+	Commented     bool   `json:"commented" yaml:"commented"`
+	Closed        bool   `json:"closed" yaml:"closed"`
+	BranchDeleted bool   `json:"branchDeleted" yaml:"branchDeleted"`
+	Error         string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+func PrunePRs() error {
+
+	ctx := context.Background()
+
+	if PRHeadRegex == "" && PRAuthor == "" && len(PRLabels) == 0 && PROlderThan == 0 {
+		return fmt.Errorf("none of the parameters 'headRegex', 'author', 'label' or 'olderThan' specified")
+	}
+	var headRegex *regexp.Regexp
+	if PRHeadRegex != "" {
+		var err error
+		if headRegex, err = regexp.Compile(PRHeadRegex); err != nil {
+			return fmt.Errorf("problem with regexp: %+v", err)
+		}
+	}
+
+	var prsToClose []*github.PullRequest
+	opts := &github.PullRequestListOptions{State: "open", ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		list, res, err := GithubClient.PullRequests.List(ctx, GithubOrgName, GithubRepo, opts)
+		if err != nil {
+			return err
+		}
+		for _, pr := range list {
+			if prPruneMatches(pr, headRegex) {
+				prsToClose = append(prsToClose, pr)
+			}
+		}
+		if res.NextPage == 0 {
+			break
+		}
+		opts.Page = res.NextPage
+	}
+
+	log.Printf("got %d PRs to close\n", len(prsToClose))
+
+	var results []*PRPruneResult
+	failed := 0
+	for _, pr := range prsToClose {
+		result := &PRPruneResult{
+			Number: pr.GetNumber(),
+			Title:  pr.GetTitle(),
+			Head:   pr.GetHead().GetRef(),
+			Age:    formatAge(time.Since(pr.GetCreatedAt().Time)),
+		}
+		results = append(results, result)
+		if DryRun {
+			continue
+		}
+		if err := prunePR(ctx, pr, result); err != nil {
+			result.Error = err.Error()
+			failed++
+		}
+	}
+
+	err := writeOutput(results, func() {
+		for _, r := range results {
+			status := "closed"
+			switch {
+			case DryRun:
+				status = "would be closed"
+			case r.Error != "":
+				status = "failed: " + r.Error
+			case r.BranchDeleted:
+				status = "closed, branch deleted"
+			}
+			fmt.Printf("#%d\t%s\t%s\t%s\n", r.Number, r.Head, r.Age, status)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to prune %d out of %d PRs", failed, len(prsToClose))
+	}
+	return nil
+}
+
+func prPruneMatches(pr *github.PullRequest, headRegex *regexp.Regexp) bool {
+	if headRegex != nil && !headRegex.MatchString(pr.GetHead().GetRef()) {
+		return false
+	}
+	if PRAuthor != "" && !strings.EqualFold(pr.GetUser().GetLogin(), PRAuthor) {
+		return false
+	}
+	if PROlderThan != 0 && time.Since(pr.GetCreatedAt().Time) < PROlderThan {
+		return false
+	}
+	for _, want := range PRLabels {
+		found := false
+		for _, l := range pr.Labels {
+			if l.GetName() == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func prunePR(ctx context.Context, pr *github.PullRequest, result *PRPruneResult) error {
+	if PRCloseComment != "" {
+		_, _, err := GithubClient.Issues.CreateComment(ctx, GithubOrgName, GithubRepo, pr.GetNumber(), &github.IssueComment{Body: github.String(PRCloseComment)})
+		if err != nil {
+			return fmt.Errorf("error when commenting: %v", err)
+		}
+		result.Commented = true
+	}
+
+	_, _, err := GithubClient.PullRequests.Edit(ctx, GithubOrgName, GithubRepo, pr.GetNumber(), &github.PullRequest{State: github.String("closed")})
+	if err != nil {
+		return fmt.Errorf("error when closing: %v", err)
+	}
+	result.Closed = true
+
+	// branches from forks can't be deleted with this repo's credentials
+	if pr.GetHead().GetRepo().GetFullName() != pr.GetBase().GetRepo().GetFullName() {
+		log.Printf("head branch of pr %d is in a different repository %s, skipping deletion", pr.GetNumber(), pr.GetHead().GetRepo().GetFullName())
+		return nil
+	}
+	_, err = GithubClient.Git.DeleteRef(ctx, GithubOrgName, GithubRepo, fmt.Sprintf("heads/%s", pr.GetHead().GetRef()))
+	if err != nil {
+		return fmt.Errorf("error when deleting branch %s: %v", pr.GetHead().GetRef(), err)
+	}
+	result.BranchDeleted = true
+
+	return nil
+}
+
+// PRUpdateResult describes the PR head before and after pr-update-branch.
+type PRUpdateResult struct {
+	Number          int    `json:"number" yaml:"number"`
+	PreviousHeadSHA string `json:"previousHeadSha" yaml:"previousHeadSha"`
+	HeadSHA         string `json:"headSha" yaml:"headSha"`
+	CheckStatus     string `json:"checkStatus,omitempty" yaml:"checkStatus,omitempty"`
+}
+
+func UpdatePRBranch() error {
+
+	ctx := context.Background()
+
+	prNumber, err := getPRNumber(ctx)
+	if err != nil {
+		return err
+	}
+
+	expectedSHA := PRExpectedSHA
+	if expectedSHA == "" {
+		pr, _, err := GithubClient.PullRequests.Get(ctx, GithubOrgName, GithubRepo, prNumber)
+		if err != nil {
+			return err
+		}
+		expectedSHA = pr.GetHead().GetSHA()
+	}
+
+	_, _, err = GithubClient.PullRequests.UpdateBranch(ctx, GithubOrgName, GithubRepo, prNumber, &github.PullRequestBranchUpdateOptions{ExpectedHeadSHA: github.String(expectedSHA)})
+	// the update is scheduled in the background and github responds with 202
+	var accepted *github.AcceptedError
+	if err != nil && !errors.As(err, &accepted) {
+		return err
+	}
+	log.Printf("update of pr %d from head %s scheduled, waiting for the new head", prNumber, expectedSHA)
+
+	deadline := time.Now().Add(WaitTimeout)
+	result := &PRUpdateResult{Number: prNumber, PreviousHeadSHA: expectedSHA}
+	for {
+		pr, _, err := GithubClient.PullRequests.Get(ctx, GithubOrgName, GithubRepo, prNumber)
+		if err != nil {
+			return err
+		}
+		if sha := pr.GetHead().GetSHA(); sha != expectedSHA {
+			result.HeadSHA = sha
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out after %s waiting for the head of pr %d to change from %s", WaitTimeout, prNumber, expectedSHA)
+		}
+		time.Sleep(WaitPollingInterval)
+	}
+
+	if PRWaitChecks {
+		log.Printf("waiting for checks on the new head %s", result.HeadSHA)
+		status, err := waitForChecks(ctx, result.HeadSHA, deadline)
+		if err != nil {
+			return err
+		}
+		result.CheckStatus = status
+	}
+
+	err = writeOutput(result, func() {
+		fmt.Printf("pr %d head updated: %s -> %s\n", result.Number, result.PreviousHeadSHA, result.HeadSHA)
+		if result.CheckStatus != "" {
+			fmt.Printf("checks: %s\n", result.CheckStatus)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if result.CheckStatus == CheckStatusFailure {
+		return fmt.Errorf("checks failed on the new head %s", result.HeadSHA)
+	}
+	return nil
+}
+
+// waitForChecks polls the check status of the given commit until the checks
+// complete. A commit without any checks reported yet is treated as pending.
+func waitForChecks(ctx context.Context, sha string, deadline time.Time) (string, error) {
+	for {
+		status, err := getCheckStatus(ctx, GithubRepo, sha)
+		if err != nil {
+			return "", err
+		}
+		if status == CheckStatusSuccess || status == CheckStatusFailure {
+			return status, nil
+		}
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("timed out after %s waiting for checks on %s, last status: %s", WaitTimeout, sha, status)
+		}
+		time.Sleep(WaitPollingInterval)
+	}
+}
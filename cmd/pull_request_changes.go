@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v52/github"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+
+	prDiff.Flags().StringVar(&GithubOrgName, "githubOrgName", "", "name of the github organization")
+	prDiff.Flags().StringVar(&GithubRepo, "githubRepoName", "", "name of the repository the PR belongs to")
+	prDiff.Flags().StringVar(&GithubBranchName, "branchName", "", "the name of the PR head branch, used when prNumber is not specified")
+	prDiff.Flags().IntVar(&GithubPRNumber, "prNumber", 0, "the number of the PR")
+	prDiff.Flags().BoolVar(&PRPatchFormat, "patch", false, "print the PR as a series of patches instead of a unified diff")
+	prDiff.MarkFlagRequired("githubOrgName")
+	prDiff.MarkFlagRequired("githubRepoName")
+
+	prDiff.Run = func(cmd *cobra.Command, args []string) {
+		if err := DiffPR(); err != nil {
+			log.Fatalf("error when getting PR diff: %v", err)
+		}
+	}
+
+	prFiles.Flags().StringVar(&GithubOrgName, "githubOrgName", "", "name of the github organization")
+	prFiles.Flags().StringVar(&GithubRepo, "githubRepoName", "", "name of the repository the PR belongs to")
+	prFiles.Flags().StringVar(&GithubBranchName, "branchName", "", "the name of the PR head branch, used when prNumber is not specified")
+	prFiles.Flags().IntVar(&GithubPRNumber, "prNumber", 0, "the number of the PR")
+	prFiles.Flags().StringVar(&FileGlob, "glob", "", "list only files matching the glob pattern, e.g. 'deploy/*.yaml' - optional")
+	prFiles.MarkFlagRequired("githubOrgName")
+	prFiles.MarkFlagRequired("githubRepoName")
+
+	prFiles.Run = func(cmd *cobra.Command, args []string) {
+		if err := ListPRFiles(); err != nil {
+			log.Fatalf("error when listing PR files: %v", err)
+		}
+	}
+
+	prCommits.Flags().StringVar(&GithubOrgName, "githubOrgName", "", "name of the github organization")
+	prCommits.Flags().StringVar(&GithubRepo, "githubRepoName", "", "name of the repository the PR belongs to")
+	prCommits.Flags().StringVar(&GithubBranchName, "branchName", "", "the name of the PR head branch, used when prNumber is not specified")
+	prCommits.Flags().IntVar(&GithubPRNumber, "prNumber", 0, "the number of the PR")
+	prCommits.MarkFlagRequired("githubOrgName")
+	prCommits.MarkFlagRequired("githubRepoName")
+
+	prCommits.Run = func(cmd *cobra.Command, args []string) {
+		if err := ListPRCommits(); err != nil {
+			log.Fatalf("error when listing PR commits: %v", err)
+		}
+	}
+}
+
+// PRFile is a single file changed in a PR.
+type PRFile struct {
+	Filename         string `json:"filename" yaml:"filename"`
+	PreviousFilename string `json:"previousFilename,omitempty" yaml:"previousFilename,omitempty"`
+	Status           string `json:"status" yaml:"status"`
+	Additions        int    `json:"additions" yaml:"additions"`
+	Deletions        int    `json:"deletions" yaml:"deletions"`
+	Changes          int    `json:"changes" yaml:"changes"`
+	SHA              string `json:"sha" yaml:"sha"`
+}
+
+// PRCommit is a single commit of a PR.
+type PRCommit struct {
+	SHA     string    `json:"sha" yaml:"sha"`
+	Author  string    `json:"author" yaml:"author"`
+	Date    time.Time `json:"date" yaml:"date"`
+	Message string    `json:"message" yaml:"message"`
+	URL     string    `json:"url" yaml:"url"`
+}
+
+func DiffPR() error {
+
+	ctx := context.Background()
+
+	prNumber, err := getPRNumber(ctx)
+	if err != nil {
+		return err
+	}
+
+	opts := github.RawOptions{Type: github.Diff}
+	if PRPatchFormat {
+		opts.Type = github.Patch
+	}
+	diff, _, err := GithubClient.PullRequests.GetRaw(ctx, GithubOrgName, GithubRepo, prNumber, opts)
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(diff)
+	return nil
+}
+
+func ListPRFiles() error {
+
+	ctx := context.Background()
+
+	if FileGlob != "" {
+		if _, err := path.Match(FileGlob, ""); err != nil {
+			return fmt.Errorf("problem with glob pattern: %+v", err)
+		}
+	}
+
+	prNumber, err := getPRNumber(ctx)
+	if err != nil {
+		return err
+	}
+
+	var files []*PRFile
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		list, res, err := GithubClient.PullRequests.ListFiles(ctx, GithubOrgName, GithubRepo, prNumber, opts)
+		if err != nil {
+			return err
+		}
+		for _, f := range list {
+			if FileGlob != "" {
+				// the pattern is validated above
+				if match, _ := path.Match(FileGlob, f.GetFilename()); !match {
+					continue
+				}
+			}
+			files = append(files, &PRFile{
+				Filename:         f.GetFilename(),
+				PreviousFilename: f.GetPreviousFilename(),
+				Status:           f.GetStatus(),
+				Additions:        f.GetAdditions(),
+				Deletions:        f.GetDeletions(),
+				Changes:          f.GetChanges(),
+				SHA:              f.GetSHA(),
+			})
+		}
+		if res.NextPage == 0 {
+			break
+		}
+		opts.Page = res.NextPage
+	}
+
+	return writeOutput(files, func() {
+		for _, f := range files {
+			fmt.Printf("%s\t+%d\t-%d\t%s\n", f.Status, f.Additions, f.Deletions, f.Filename)
+		}
+	})
+}
+
+func ListPRCommits() error {
+
+	ctx := context.Background()
+
+	prNumber, err := getPRNumber(ctx)
+	if err != nil {
+		return err
+	}
+
+	var commits []*PRCommit
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		list, res, err := GithubClient.PullRequests.ListCommits(ctx, GithubOrgName, GithubRepo, prNumber, opts)
+		if err != nil {
+			return err
+		}
+		for _, c := range list {
+			author := c.GetAuthor().GetLogin()
+			if author == "" {
+				author = c.GetCommit().GetAuthor().GetName()
+			}
+			commits = append(commits, &PRCommit{
+				SHA:     c.GetSHA(),
+				Author:  author,
+				Date:    c.GetCommit().GetAuthor().GetDate().Time,
+				Message: c.GetCommit().GetMessage(),
+				URL:     c.GetHTMLURL(),
+			})
+		}
+		if res.NextPage == 0 {
+			break
+		}
+		opts.Page = res.NextPage
+	}
+
+	return writeOutput(commits, func() {
+		for _, c := range commits {
+			subject := strings.SplitN(c.Message, "\n", 2)[0]
+			fmt.Printf("%s\t%s\t%s\n", c.SHA[:7], c.Author, subject)
+		}
+	})
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v52/github"
+	"github.com/spf13/cobra"
+)
+
+const (
+	ReviewEventApprove        = "APPROVE"
+	ReviewEventRequestChanges = "REQUEST_CHANGES"
+	ReviewEventComment        = "COMMENT"
+)
+
+func init() {
+
+	prReview.Flags().StringVar(&GithubOrgName, "githubOrgName", "", "name of the github organization")
+	prReview.Flags().StringVar(&GithubRepo, "githubRepoName", "", "name of the repository the PR belongs to")
+	prReview.Flags().StringVar(&GithubBranchName, "branchName", "", "the name of the PR head branch, used when prNumber is not specified")
+	prReview.Flags().IntVar(&GithubPRNumber, "prNumber", 0, "the number of the PR")
+	prReview.Flags().StringVar(&ReviewEvent, "event", ReviewEventComment, fmt.Sprintf("the review action, one of: %s, %s, %s", ReviewEventApprove, ReviewEventRequestChanges, ReviewEventComment))
+	prReview.Flags().StringVar(&ReviewBody, "body", "", "the body text of the review")
+	prReview.Flags().StringVar(&ReviewCommentsFile, "commentsFile", "", "path to a JSON file with a list of inline comments, e.g. [{\"path\": \"main.go\", \"line\": 10, \"body\": \"nit\"}] - optional")
+	prReview.MarkFlagRequired("githubOrgName")
+	prReview.MarkFlagRequired("githubRepoName")
+
+	prReview.Run = func(cmd *cobra.Command, args []string) {
+		if err := ReviewPR(); err != nil {
+			log.Fatalf("error when reviewing PR: %v", err)
+		}
+	}
+
+	prReviews.Flags().StringVar(&GithubOrgName, "githubOrgName", "", "name of the github organization")
+	prReviews.Flags().StringVar(&GithubRepo, "githubRepoName", "", "name of the repository the PR belongs to")
+	prReviews.Flags().StringVar(&GithubBranchName, "branchName", "", "the name of the PR head branch, used when prNumber is not specified")
+	prReviews.Flags().IntVar(&GithubPRNumber, "prNumber", 0, "the number of the PR")
+	prReviews.MarkFlagRequired("githubOrgName")
+	prReviews.MarkFlagRequired("githubRepoName")
+
+	prReviews.Run = func(cmd *cobra.Command, args []string) {
+		if err := ListPRReviews(); err != nil {
+			log.Fatalf("error when listing PR reviews: %v", err)
+		}
+	}
+}
+
+// PRReview is a summary of a single PR review.
+type PRReview struct {
+	ID          int64     `json:"id" yaml:"id"`
+	PRNumber    int       `json:"prNumber" yaml:"prNumber"`
+	Author      string    `json:"author" yaml:"author"`
+	State       string    `json:"state" yaml:"state"`
+	CommitID    string    `json:"commitId" yaml:"commitId"`
+	SubmittedAt time.Time `json:"submittedAt" yaml:"submittedAt"`
+	URL         string    `json:"url" yaml:"url"`
+	Body        string    `json:"body,omitempty" yaml:"body,omitempty"`
+}
+
+func newPRReview(prNumber int, r *github.PullRequestReview) *PRReview {
+	return &PRReview{
+		ID:          r.GetID(),
+		PRNumber:    prNumber,
+		Author:      r.GetUser().GetLogin(),
+		State:       r.GetState(),
+		CommitID:    r.GetCommitID(),
+		SubmittedAt: r.GetSubmittedAt().Time,
+		URL:         r.GetHTMLURL(),
+		Body:        r.GetBody(),
+	}
+}
+
+func ReviewPR() error {
+
+	ctx := context.Background()
+
+	event := strings.ToUpper(ReviewEvent)
+	switch event {
+	case ReviewEventApprove, ReviewEventRequestChanges, ReviewEventComment:
+	default:
+		return fmt.Errorf("unsupported review event '%s'", ReviewEvent)
+	}
+
+	var comments []*github.DraftReviewComment
+	if ReviewCommentsFile != "" {
+		data, err := os.ReadFile(ReviewCommentsFile)
+		if err != nil {
+			return fmt.Errorf("error when reading review comments file: %v", err)
+		}
+		if err := json.Unmarshal(data, &comments); err != nil {
+			return fmt.Errorf("error when parsing review comments file %s: %v", ReviewCommentsFile, err)
+		}
+	}
+
+	prNumber, err := getPRNumber(ctx)
+	if err != nil {
+		return err
+	}
+
+	review := &github.PullRequestReviewRequest{
+		Event:    github.String(event),
+		Comments: comments,
+	}
+	if ReviewBody != "" {
+		review.Body = github.String(ReviewBody)
+	}
+	created, _, err := GithubClient.PullRequests.CreateReview(ctx, GithubOrgName, GithubRepo, prNumber, review)
+	if err != nil {
+		return err
+	}
+
+	r := newPRReview(prNumber, created)
+	return writeOutput(r, func() {
+		fmt.Printf("review %d (%s) with %d inline comments submitted on pr %d: %s\n", r.ID, r.State, len(comments), prNumber, r.URL)
+	})
+}
+
+func ListPRReviews() error {
+
+	ctx := context.Background()
+
+	prNumber, err := getPRNumber(ctx)
+	if err != nil {
+		return err
+	}
+
+	var reviews []*PRReview
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		list, res, err := GithubClient.PullRequests.ListReviews(ctx, GithubOrgName, GithubRepo, prNumber, opts)
+		if err != nil {
+			return err
+		}
+		for _, r := range list {
+			reviews = append(reviews, newPRReview(prNumber, r))
+		}
+		if res.NextPage == 0 {
+			break
+		}
+		opts.Page = res.NextPage
+	}
+
+	return writeOutput(reviews, func() {
+		for _, r := range reviews {
+			fmt.Printf("%d\t%s\t%s\t%s\n", r.ID, r.State, r.Author, r.SubmittedAt.Format(time.RFC3339))
+		}
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/google/go-github/v52/github"
 	"github.com/spf13/cobra"
@@ -15,6 +16,8 @@ import (
 
 var (
 	GithubTokenKey string = "github_token"
+	SignPassKey    string = "sign_passphrase"
+	WebhookSecKey  string = "webhook_secret"
 	GithubToken    string
 	GithubClient   *github.Client
 
@@ -22,16 +25,125 @@ var (
 	GithubRepo    string
 	RepoFilter    string
 
-	FilePath    string
-	FileContent string
+	FilePath       string
+	FileContent    string
+	FileContentSet bool
+	FileFromPath   string
+	FileFromStdin  bool
+	FileRef        string
+	FileDest       string
+	FileRecursive  bool
+	FileRaw        bool
+
+	PatchSet       []string
+	PatchMergeFile string
+	PatchDocument  int
+
+	SyncLocalDir  string
+	SyncRemoteDir string
+	SyncDelete    bool
 
 	GithubBranchName      string
 	GithubNewBranchName   string
 	GithubBaseBranchName  string
 	GithubBaseBranchSHA   string
 	GithubBranchNameRegex string
+
+	GithubPRNumber int
+
+	CommentPattern      string
+	CommentAuthor       string
+	CommentSince        time.Duration
+	WaitTimeout         time.Duration
+	WaitPollingInterval time.Duration
+	WebhookTestTimeout  time.Duration
+	WebhookTestInterval time.Duration
+
+	ReviewEvent        string
+	ReviewBody         string
+	ReviewCommentsFile string
+
+	PRState        string
+	PRBase         string
+	PRHead         string
+	PRAuthor       string
+	PRLabels       []string
+	PRDraft        string
+	PRCreated      string
+	PRUpdated      string
+	PRSearchFilter []string
+	PRHeadRegex    string
+	PROlderThan    time.Duration
+	PRCloseComment string
+	PRExpectedSHA  string
+	PRWaitChecks   bool
+	PRDetails      bool
+
+	PRPatchFormat bool
+	FileGlob      string
+
+	IssueNumber    int
+	LabelNames     []string
+	LabelsFile     string
+	LabelsDelete   bool
+	GithubRepos    []string
+	MilestoneTitle string
+
+	CommitPuts     []string
+	CommitDeletes  []string
+	CommitManifest string
+	CommitMessage  string
+	AuthorName     string
+	AuthorEmail    string
+	CommitterName  string
+	CommitterEmail string
+	SignKeyPath    string
+	SignPassphrase string
+	CommitSHA      string
+	MaxRetries     int
+
+	DryRun bool
+
+	WebhookURL         string
+	WebhookEvents      []string
+	WebhookSecret      string
+	WebhookSecretFile  string
+	WebhookContentType string
+	WebhookInsecureSSL bool
+	WebhookActive      bool
+	WebhookPruneRegex  string
+	WebhookOrgLevel    bool
+	WebhookID          int64
+	WebhookDeliveryID  int64
+	WebhookListSince   time.Duration
+	WebhookRetrySince  time.Duration
+	WebhookUntil       time.Duration
+	WebhookLimit       int
+	WebhookFailedOnly  bool
+	WebhookListenAddr  string
+	WebhookPath        string
+	WebhookExec        []string
+	WebhookForwardURL  string
+	WebhookRecordDir   string
+	WebhookFiles       []string
+	WebhookDir         string
+	WebhookEventName   string
+	WebhookAction      string
+	WebhookSender      string
+	WebhookComment     string
+	WebhookMerged      bool
+	WebhookPRNumber    int
+	WebhookBaseBranch  string
+	WebhookCheckName   string
+	WebhookConclusion  string
+	WebhookOutDir      string
+
+	OutputFormat string
 )
 
+// offlineAnnotation marks commands which do not talk to the Github API.
+const offlineAnnotation = "offline"
+
 var rootCmd = &cobra.Command{
 	Use:   "ggh",
 	Short: "ggh helper to do github stuff via cli",
@@ -93,16 +205,203 @@ var prComment = &cobra.Command{
 	// },
 }
 
+var prWaitComment = &cobra.Command{
+	Use:   "pr-wait-comment",
+	Short: "Wait for a comment matching a pattern on Github PR",
+	// Run: func(cmd *cobra.Command, args []string) {
+	// },
+}
+
+var prList = &cobra.Command{
+	Use:   "pr-list",
+	Short: "List Github PRs matching search qualifiers",
+	Long: `List Github PRs matching search qualifiers.
+
+The PRs are found via the Github search API which returns at most 1000
+results per query, narrow the query down e.g. with --created when more
+PRs match. The head, base, mergeable state and check status need three
+more API calls per PR and are only fetched with --details.`,
+	// Run: func(cmd *cobra.Command, args []string) {
+	// },
+}
+
+var prPrune = &cobra.Command{
+	Use:   "pr-prune",
+	Short: "Close stale Github PRs and delete their head branches",
+	// Run: func(cmd *cobra.Command, args []string) {
+	// },
+}
+
+var prDiff = &cobra.Command{
+	Use:   "pr-diff",
+	Short: "Print the diff of Github PR",
+	// Run: func(cmd *cobra.Command, args []string) {
+	// },
+}
+
+var prFiles = &cobra.Command{
+	Use:   "pr-files",
+	Short: "List files changed in Github PR",
+	// Run: func(cmd *cobra.Command, args []string) {
+	// },
+}
+
+var prCommits = &cobra.Command{
+	Use:   "pr-commits",
+	Short: "List commits of Github PR",
+	// Run: func(cmd *cobra.Command, args []string) {
+	// },
+}
+
+var prUpdateBranch = &cobra.Command{
+	Use:   "pr-update-branch",
+	Short: "Update Github PR head branch with the latest changes from the base branch",
+	// Run: func(cmd *cobra.Command, args []string) {
+	// },
+}
+
+var prReview = &cobra.Command{
+	Use:   "pr-review",
+	Short: "Review Github PR: approve, request changes or comment",
+	// Run: func(cmd *cobra.Command, args []string) {
+	// },
+}
+
+var prReviews = &cobra.Command{
+	Use:   "pr-reviews",
+	Short: "List reviews of Github PR",
+	// Run: func(cmd *cobra.Command, args []string) {
+	// },
+}
+
+var labelCmd = &cobra.Command{
+	Use:   "label",
+	Short: "Manage labels of Github PRs, issues and repos",
+}
+
+var labelAdd = &cobra.Command{
+	Use:   "add",
+	Short: "Add labels to Github PR or issue",
+	// Run: func(cmd *cobra.Command, args []string) {
+	// },
+}
+
+var labelRemove = &cobra.Command{
+	Use:   "remove",
+	Short: "Remove labels from Github PR or issue",
+	// Run: func(cmd *cobra.Command, args []string) {
+	// },
+}
+
+var labelSet = &cobra.Command{
+	Use:   "set",
+	Short: "Replace all labels of Github PR or issue",
+	// Run: func(cmd *cobra.Command, args []string) {
+	// },
+}
+
+var labelSync = &cobra.Command{
+	Use:   "sync",
+	Short: "Sync labels defined in a YAML file to Github repos",
+	// Run: func(cmd *cobra.Command, args []string) {
+	// },
+}
+
+var milestoneCmd = &cobra.Command{
+	Use:   "milestone",
+	Short: "Manage milestones of Github PRs and issues",
+}
+
+var milestoneSet = &cobra.Command{
+	Use:   "set",
+	Short: "Set or clear the milestone of Github PR or issue",
+	// Run: func(cmd *cobra.Command, args []string) {
+	// },
+}
+
+var commitCmd = &cobra.Command{
+	Use:   "commit",
+	Short: "Commit multiple file changes to a Github branch at once",
+	// Run: func(cmd *cobra.Command, args []string) {
+	// },
+}
+
+var commitVerify = &cobra.Command{
+	Use:   "commit-verify",
+	Short: "Report the signature verification status of a commit",
+	// Run: func(cmd *cobra.Command, args []string) {
+	// },
+}
+
 var webhookConfig = &cobra.Command{
 	Use:   "webhook-config",
-	Short: "Configure Github webhook for a repo",
+	Short: "Configure Github webhook for a repo or an organization",
 	// Run: func(cmd *cobra.Command, args []string) {
 	// },
 }
 
 var webhookList = &cobra.Command{
 	Use:   "webhook-list",
-	Short: "List Github webhooks for a repo",
+	Short: "List Github webhooks for a repo or an organization",
+	// Run: func(cmd *cobra.Command, args []string) {
+	// },
+}
+
+var webhookDelete = &cobra.Command{
+	Use:   "webhook-delete",
+	Short: "Delete Github webhook from a repo or an organization",
+	// Run: func(cmd *cobra.Command, args []string) {
+	// },
+}
+
+var webhookDeliveries = &cobra.Command{
+	Use:   "webhook-deliveries",
+	Short: "List recent deliveries of a Github webhook",
+	// Run: func(cmd *cobra.Command, args []string) {
+	// },
+}
+
+var webhookDeliveryGet = &cobra.Command{
+	Use:   "webhook-delivery-get",
+	Short: "Show the request and response of a Github webhook delivery",
+	// Run: func(cmd *cobra.Command, args []string) {
+	// },
+}
+
+var webhookRedeliver = &cobra.Command{
+	Use:   "webhook-redeliver",
+	Short: "Redeliver failed deliveries of a Github webhook",
+	// Run: func(cmd *cobra.Command, args []string) {
+	// },
+}
+
+var webhookServe = &cobra.Command{
+	Use:         "webhook-serve",
+	Short:       "Run a local receiver verifying and logging Github webhook deliveries",
+	Annotations: map[string]string{offlineAnnotation: "true"},
+	// Run: func(cmd *cobra.Command, args []string) {
+	// },
+}
+
+var webhookTest = &cobra.Command{
+	Use:   "webhook-test",
+	Short: "Ping a Github webhook and wait for the endpoint to respond",
+	// Run: func(cmd *cobra.Command, args []string) {
+	// },
+}
+
+var webhookReplay = &cobra.Command{
+	Use:         "webhook-replay",
+	Short:       "Send recorded webhook payloads signed to a local consumer",
+	Annotations: map[string]string{offlineAnnotation: "true"},
+	// Run: func(cmd *cobra.Command, args []string) {
+	// },
+}
+
+var webhookGenerate = &cobra.Command{
+	Use:         "webhook-generate",
+	Short:       "Generate synthetic Github webhook payloads",
+	Annotations: map[string]string{offlineAnnotation: "true"},
 	// Run: func(cmd *cobra.Command, args []string) {
 	// },
 }
@@ -121,6 +420,34 @@ var fileUpdate = &cobra.Command{
 	// },
 }
 
+var filePut = &cobra.Command{
+	Use:   "file-put",
+	Short: "create or update a file in github repo",
+	// Run: func(cmd *cobra.Command, args []string) {
+	// },
+}
+
+var fileGet = &cobra.Command{
+	Use:   "file-get",
+	Short: "print or download a file or directory from a github repo",
+	// Run: func(cmd *cobra.Command, args []string) {
+	// },
+}
+
+var filePatch = &cobra.Command{
+	Use:   "file-patch",
+	Short: "edit values in a YAML or JSON file in a github repo",
+	// Run: func(cmd *cobra.Command, args []string) {
+	// },
+}
+
+var dirSync = &cobra.Command{
+	Use:   "dir-sync",
+	Short: "sync a local directory into a github repo branch in a single commit",
+	// Run: func(cmd *cobra.Command, args []string) {
+	// },
+}
+
 var fileDelete = &cobra.Command{
 	Use:   "file-delete",
 	Short: "delete a file from a github repo",
@@ -139,25 +466,63 @@ func init() {
 	rootCmd.AddCommand(repoDelete)
 	rootCmd.AddCommand(webhookConfig)
 	rootCmd.AddCommand(webhookList)
+	rootCmd.AddCommand(webhookDelete)
+	rootCmd.AddCommand(webhookDeliveries)
+	rootCmd.AddCommand(webhookDeliveryGet)
+	rootCmd.AddCommand(webhookRedeliver)
+	rootCmd.AddCommand(webhookServe)
+	rootCmd.AddCommand(webhookTest)
+	rootCmd.AddCommand(webhookReplay)
+	rootCmd.AddCommand(webhookGenerate)
 	rootCmd.AddCommand(fileCreate)
 	rootCmd.AddCommand(fileUpdate)
+	rootCmd.AddCommand(filePut)
+	rootCmd.AddCommand(fileGet)
+	rootCmd.AddCommand(filePatch)
+	rootCmd.AddCommand(dirSync)
 	rootCmd.AddCommand(fileDelete)
+	rootCmd.AddCommand(commitCmd)
+	rootCmd.AddCommand(commitVerify)
 	rootCmd.AddCommand(branchDelete)
 	rootCmd.AddCommand(branchCreate)
 	rootCmd.AddCommand(prGet)
 	rootCmd.AddCommand(prMerge)
 	rootCmd.AddCommand(prComment)
+	rootCmd.AddCommand(prWaitComment)
+	rootCmd.AddCommand(prList)
+	rootCmd.AddCommand(prPrune)
+	rootCmd.AddCommand(prDiff)
+	rootCmd.AddCommand(prFiles)
+	rootCmd.AddCommand(prCommits)
+	rootCmd.AddCommand(prUpdateBranch)
+	rootCmd.AddCommand(prReview)
+	rootCmd.AddCommand(prReviews)
 	rootCmd.AddCommand(branchListChecks)
 	rootCmd.AddCommand(branchList)
 
+	labelCmd.AddCommand(labelAdd)
+	labelCmd.AddCommand(labelRemove)
+	labelCmd.AddCommand(labelSet)
+	labelCmd.AddCommand(labelSync)
+	rootCmd.AddCommand(labelCmd)
+	milestoneCmd.AddCommand(milestoneSet)
+	rootCmd.AddCommand(milestoneCmd)
+
 	rootCmd.PersistentFlags().StringVarP(&GithubToken, "token", "t", "", fmt.Sprintf("Github access token. Can be set via the %s env var.", strings.ToUpper(GithubTokenKey)))
 	viper.BindPFlag(GithubTokenKey, rootCmd.PersistentFlags().Lookup("token"))
+	rootCmd.PersistentFlags().StringVarP(&OutputFormat, "output", "o", OutputText, fmt.Sprintf("Output format. One of: %s, %s, %s.", OutputText, OutputJSON, OutputYAML))
 
-	cobra.OnInitialize(initGithubClient)
+	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		viper.AutomaticEnv() // read in environment variables that match
+		// commands working on local payloads only can be used without a token
+		if cmd.Annotations[offlineAnnotation] == "true" {
+			return
+		}
+		initGithubClient()
+	}
 }
 
 func initGithubClient() {
-	viper.AutomaticEnv() // read in environment variables that match
 	token := viper.GetString(GithubTokenKey)
 	if token == "" {
 		log.Fatalln("Github token not defined. See usage.")
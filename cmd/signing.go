@@ -0,0 +1,247 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/google/go-github/v52/github"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	sshSigNamespace = "git"
+	sshSigHashAlgo  = "sha512"
+)
+
+func init() {
+
+	commitVerify.Flags().StringVar(&GithubOrgName, "githubOrgName", "", "name of the github organization")
+	commitVerify.Flags().StringVar(&GithubRepo, "githubRepoName", "", "name of the repository the commit belongs to")
+	commitVerify.Flags().StringVar(&CommitSHA, "sha", "", "the commit SHA to verify")
+	commitVerify.Flags().StringVar(&GithubBranchName, "branchName", "", "verify the head commit of the branch when sha is not specified")
+	commitVerify.MarkFlagRequired("githubOrgName")
+	commitVerify.MarkFlagRequired("githubRepoName")
+
+	commitVerify.Run = func(cmd *cobra.Command, args []string) {
+		if err := VerifyCommit(); err != nil {
+			log.Fatalf("error when verifying commit: %v", err)
+		}
+	}
+}
+
+// commitSigner signs commits created via the Git Data API.
+type commitSigner interface {
+	// author returns the identity the key belongs to, used when no author is given.
+	author() (*github.CommitAuthor, error)
+	sign(commit *github.Commit) error
+}
+
+// gpgSigner lets go-github create an OpenPGP signature of the commit.
+type gpgSigner struct {
+	entity *openpgp.Entity
+}
+
+func (s *gpgSigner) author() (*github.CommitAuthor, error) {
+	identity := s.entity.PrimaryIdentity()
+	if identity == nil || identity.UserId == nil || identity.UserId.Email == "" {
+		return nil, fmt.Errorf("the signing key has no identity with an email, specify 'author-name' and 'author-email'")
+	}
+	return commitIdentity("author", identity.UserId.Name, identity.UserId.Email)
+}
+
+func (s *gpgSigner) sign(commit *github.Commit) error {
+	commit.SigningKey = s.entity
+	return nil
+}
+
+// sshSigner creates an SSH signature of the commit in the format used by
+// 'git commit -S' with gpg.format=ssh.
+type sshSigner struct {
+	signer ssh.Signer
+}
+
+func (s *sshSigner) author() (*github.CommitAuthor, error) {
+	return nil, fmt.Errorf("SSH keys carry no identity, specify 'author-name' and 'author-email' to sign commits")
+}
+
+func (s *sshSigner) sign(commit *github.Commit) error {
+	hash := sha512.Sum512([]byte(commitPayload(commit)))
+
+	signedData := []byte("SSHSIG")
+	signedData = appendSSHString(signedData, []byte(sshSigNamespace))
+	signedData = appendSSHString(signedData, nil)
+	signedData = appendSSHString(signedData, []byte(sshSigHashAlgo))
+	signedData = appendSSHString(signedData, hash[:])
+
+	var sig *ssh.Signature
+	var err error
+	if algorithmSigner, ok := s.signer.(ssh.AlgorithmSigner); ok && s.signer.PublicKey().Type() == ssh.KeyAlgoRSA {
+		// plain ssh-rsa signatures use SHA-1 which is not accepted anymore
+		sig, err = algorithmSigner.SignWithAlgorithm(rand.Reader, signedData, ssh.KeyAlgoRSASHA512)
+	} else {
+		sig, err = s.signer.Sign(rand.Reader, signedData)
+	}
+	if err != nil {
+		return fmt.Errorf("error when signing commit: %v", err)
+	}
+
+	blob := []byte("SSHSIG")
+	blob = binary.BigEndian.AppendUint32(blob, 1)
+	blob = appendSSHString(blob, s.signer.PublicKey().Marshal())
+	blob = appendSSHString(blob, []byte(sshSigNamespace))
+	blob = appendSSHString(blob, nil)
+	blob = appendSSHString(blob, []byte(sshSigHashAlgo))
+	blob = appendSSHString(blob, ssh.Marshal(sig))
+
+	encoded := base64.StdEncoding.EncodeToString(blob)
+	var armored strings.Builder
+	armored.WriteString("-----BEGIN SSH SIGNATURE-----\n")
+	for len(encoded) > 70 {
+		armored.WriteString(encoded[:70] + "\n")
+		encoded = encoded[70:]
+	}
+	armored.WriteString(encoded + "\n")
+	armored.WriteString("-----END SSH SIGNATURE-----\n")
+
+	commit.Verification = &github.SignatureVerification{Signature: github.String(armored.String())}
+	return nil
+}
+
+func appendSSHString(b, s []byte) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+// commitPayload returns the raw git commit object the signature is made for,
+// built the same way go-github does it for OpenPGP signatures.
+func commitPayload(commit *github.Commit) string {
+	var lines []string
+	lines = append(lines, fmt.Sprintf("tree %s", commit.GetTree().GetSHA()))
+	for _, parent := range commit.Parents {
+		lines = append(lines, fmt.Sprintf("parent %s", parent.GetSHA()))
+	}
+
+	author := commit.GetAuthor()
+	lines = append(lines, fmt.Sprintf("author %s <%s> %d %s", author.GetName(), author.GetEmail(), author.GetDate().Unix(), author.GetDate().Format("-0700")))
+	committer := commit.Committer
+	if committer == nil {
+		committer = author
+	}
+	// there needs to be an empty line between the headers and the message
+	lines = append(lines, fmt.Sprintf("committer %s <%s> %d %s\n", committer.GetName(), committer.GetEmail(), committer.GetDate().Unix(), committer.GetDate().Format("-0700")))
+	lines = append(lines, commit.GetMessage())
+
+	return strings.Join(lines, "\n")
+}
+
+// loadCommitSigner loads the key given via --sign-key, it returns nil when
+// commits should not be signed. Both armored OpenPGP and OpenSSH private
+// keys are supported.
+func loadCommitSigner() (commitSigner, error) {
+	if SignKeyPath == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(SignKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("error when reading signing key: %v", err)
+	}
+	passphrase := SignPassphrase
+	if passphrase == "" {
+		passphrase = viper.GetString(SignPassKey)
+	}
+
+	if bytes.Contains(data, []byte("BEGIN PGP PRIVATE KEY BLOCK")) {
+		entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("error when parsing signing key %s: %v", SignKeyPath, err)
+		}
+		entity := entities[0]
+		if entity.PrivateKey == nil {
+			return nil, fmt.Errorf("%s does not contain a private key", SignKeyPath)
+		}
+		keys := []*openpgp.Key{{PrivateKey: entity.PrivateKey}}
+		for i := range entity.Subkeys {
+			keys = append(keys, &openpgp.Key{PrivateKey: entity.Subkeys[i].PrivateKey})
+		}
+		for _, k := range keys {
+			if k.PrivateKey == nil || !k.PrivateKey.Encrypted {
+				continue
+			}
+			if err := k.PrivateKey.Decrypt([]byte(passphrase)); err != nil {
+				return nil, fmt.Errorf("error when decrypting signing key %s: %v", SignKeyPath, err)
+			}
+		}
+		return &gpgSigner{entity: entity}, nil
+	}
+
+	signer, err := ssh.ParsePrivateKey(data)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(data, []byte(passphrase))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error when parsing signing key %s: %v", SignKeyPath, err)
+	}
+	return &sshSigner{signer: signer}, nil
+}
+
+// CommitVerification is the signature verification status of a commit.
+type CommitVerification struct {
+	SHA      string `json:"sha" yaml:"sha"`
+	Verified bool   `json:"verified" yaml:"verified"`
+	Reason   string `json:"reason" yaml:"reason"`
+	Author   string `json:"author" yaml:"author"`
+	URL      string `json:"url" yaml:"url"`
+}
+
+func VerifyCommit() error {
+
+	ctx := context.Background()
+
+	sha := CommitSHA
+	if sha == "" {
+		if GithubBranchName == "" {
+			return fmt.Errorf("none of the parameters 'sha' or 'branchName' specified")
+		}
+		ref, _, err := GithubClient.Git.GetRef(ctx, GithubOrgName, GithubRepo, fmt.Sprintf("heads/%s", GithubBranchName))
+		if err != nil {
+			return fmt.Errorf("error getting branch %s: %+v", GithubBranchName, err)
+		}
+		sha = ref.GetObject().GetSHA()
+	}
+
+	commit, _, err := GithubClient.Git.GetCommit(ctx, GithubOrgName, GithubRepo, sha)
+	if err != nil {
+		return fmt.Errorf("error getting commit %s: %+v", sha, err)
+	}
+
+	v := &CommitVerification{
+		SHA:      commit.GetSHA(),
+		Verified: commit.GetVerification().GetVerified(),
+		Reason:   commit.GetVerification().GetReason(),
+		Author:   fmt.Sprintf("%s <%s>", commit.GetAuthor().GetName(), commit.GetAuthor().GetEmail()),
+		URL:      commit.GetHTMLURL(),
+	}
+	err = writeOutput(v, func() {
+		fmt.Printf("commit %s by %s verified: %t (%s)\n", v.SHA, v.Author, v.Verified, v.Reason)
+	})
+	if err != nil {
+		return err
+	}
+	if !v.Verified {
+		return fmt.Errorf("commit %s is not verified: %s", v.SHA, v.Reason)
+	}
+	return nil
+}
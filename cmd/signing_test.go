@@ -0,0 +1,246 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/google/go-github/v52/github"
+	"golang.org/x/crypto/ssh"
+)
+
+// testCommit returns a commit as commitChanges builds it before signing.
+func testCommit() *github.Commit {
+	date := &github.Timestamp{Time: time.Date(2023, 5, 1, 12, 30, 0, 0, time.FixedZone("", 2*60*60))}
+	return &github.Commit{
+		Message: github.String("update files\n\nwith a body"),
+		Tree:    &github.Tree{SHA: github.String("4b825dc642cb6eb9a060e54bf8d69288fbee4904")},
+		Parents: []*github.Commit{{SHA: github.String("8d1f6bc1cc3ecb35bf8f1b4f29aeaf8b3cd4ce25")}},
+		Author:  &github.CommitAuthor{Name: github.String("Jane Doe"), Email: github.String("jane@example.com"), Date: date},
+	}
+}
+
+// writeSigningKey writes the key to a temporary file and points --sign-key to it.
+func writeSigningKey(t *testing.T, key []byte) {
+	t.Helper()
+	SignKeyPath = filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(SignKeyPath, key, 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SignKeyPath = "" })
+}
+
+// createSignedCommit sends the commit to a fake API and returns the signature
+// of the request, so the signature go-github creates for OpenPGP keys is
+// covered as well.
+func createSignedCommit(t *testing.T, commit *github.Commit) string {
+	t.Helper()
+	var signature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Signature string `json:"signature"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("error when decoding request: %v", err)
+		}
+		signature = body.Signature
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"sha":"0000000000000000000000000000000000000000"}`))
+	}))
+	defer server.Close()
+
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+	if _, _, err := client.Git.CreateCommit(context.Background(), "org", "repo", commit); err != nil {
+		t.Fatalf("error when creating commit: %v", err)
+	}
+	if signature == "" {
+		t.Fatal("the commit was sent without a signature")
+	}
+	return signature
+}
+
+// sshSignedData returns the data an SSHSIG of the payload is made for.
+func sshSignedData(payload string) []byte {
+	hash := sha512.Sum512([]byte(payload))
+	signedData := []byte("SSHSIG")
+	signedData = appendSSHString(signedData, []byte(sshSigNamespace))
+	signedData = appendSSHString(signedData, nil)
+	signedData = appendSSHString(signedData, []byte(sshSigHashAlgo))
+	return appendSSHString(signedData, hash[:])
+}
+
+// verifySSHSignature checks an armored SSHSIG the way 'ssh-keygen -Y verify' does.
+func verifySSHSignature(t *testing.T, publicKey ssh.PublicKey, payload, armored string) *ssh.Signature {
+	t.Helper()
+	encoded := strings.TrimPrefix(armored, "-----BEGIN SSH SIGNATURE-----\n")
+	encoded = strings.TrimSuffix(encoded, "-----END SSH SIGNATURE-----\n")
+	blob, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(encoded, "\n", ""))
+	if err != nil {
+		t.Fatalf("error when decoding signature: %v", err)
+	}
+
+	var sshsig struct {
+		MagicPreamble [6]byte
+		Version       uint32
+		PublicKey     string
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Signature     string
+	}
+	if err := ssh.Unmarshal(blob, &sshsig); err != nil {
+		t.Fatalf("error when parsing signature: %v", err)
+	}
+	if string(sshsig.MagicPreamble[:]) != "SSHSIG" || sshsig.Version != 1 {
+		t.Fatalf("unexpected preamble %q version %d", sshsig.MagicPreamble, sshsig.Version)
+	}
+	if sshsig.Namespace != sshSigNamespace || sshsig.HashAlgorithm != sshSigHashAlgo {
+		t.Fatalf("unexpected namespace %q hash %q", sshsig.Namespace, sshsig.HashAlgorithm)
+	}
+	if !bytes.Equal([]byte(sshsig.PublicKey), publicKey.Marshal()) {
+		t.Fatal("the signature carries another public key")
+	}
+
+	sig := &ssh.Signature{}
+	if err := ssh.Unmarshal([]byte(sshsig.Signature), sig); err != nil {
+		t.Fatalf("error when parsing signature: %v", err)
+	}
+	if err := publicKey.Verify(sshSignedData(payload), sig); err != nil {
+		t.Fatalf("the signature does not verify: %v", err)
+	}
+	return sig
+}
+
+func TestSSHSignerRoundTrip(t *testing.T) {
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(ed25519Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		key    []byte
+		format string
+	}{
+		{
+			name:   "ed25519",
+			key:    pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}),
+			format: ssh.KeyAlgoED25519,
+		},
+		{
+			// plain ssh-rsa would be a SHA-1 signature
+			name:   "rsa",
+			key:    pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}),
+			format: ssh.KeyAlgoRSASHA512,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeSigningKey(t, tt.key)
+			signer, err := loadCommitSigner()
+			if err != nil {
+				t.Fatalf("error when loading key: %v", err)
+			}
+			s, ok := signer.(*sshSigner)
+			if !ok {
+				t.Fatalf("got signer %T, want *sshSigner", signer)
+			}
+			if _, err := s.author(); err == nil {
+				t.Error("expected an error for the author of an SSH key")
+			}
+
+			commit := testCommit()
+			if err := s.sign(commit); err != nil {
+				t.Fatalf("error when signing: %v", err)
+			}
+			signature := createSignedCommit(t, commit)
+			if signature != commit.GetVerification().GetSignature() {
+				t.Errorf("sent signature %q, want %q", signature, commit.GetVerification().GetSignature())
+			}
+
+			sig := verifySSHSignature(t, s.signer.PublicKey(), commitPayload(commit), signature)
+			if sig.Format != tt.format {
+				t.Errorf("got signature format %s, want %s", sig.Format, tt.format)
+			}
+
+			// a changed commit must not verify with the same signature
+			commit.Message = github.String("another message")
+			if err := s.signer.PublicKey().Verify(sshSignedData(commitPayload(commit)), sig); err == nil {
+				t.Error("the signature verifies for a changed commit")
+			}
+		})
+	}
+}
+
+func TestGPGSignerRoundTrip(t *testing.T) {
+	entity, err := openpgp.NewEntity("Jane Doe", "", "jane@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var key bytes.Buffer
+	w, err := armor.Encode(&key, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.SerializePrivate(w, nil); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	writeSigningKey(t, key.Bytes())
+	signer, err := loadCommitSigner()
+	if err != nil {
+		t.Fatalf("error when loading key: %v", err)
+	}
+	if _, ok := signer.(*gpgSigner); !ok {
+		t.Fatalf("got signer %T, want *gpgSigner", signer)
+	}
+	author, err := signer.author()
+	if err != nil {
+		t.Fatalf("error when getting author: %v", err)
+	}
+	if author.GetName() != "Jane Doe" || author.GetEmail() != "jane@example.com" {
+		t.Errorf("got author %s <%s>", author.GetName(), author.GetEmail())
+	}
+
+	commit := testCommit()
+	if err := signer.sign(commit); err != nil {
+		t.Fatalf("error when signing: %v", err)
+	}
+	signature := createSignedCommit(t, commit)
+
+	keyring := openpgp.EntityList{entity}
+	if _, err := openpgp.CheckArmoredDetachedSignature(keyring, strings.NewReader(commitPayload(commit)), strings.NewReader(signature), nil); err != nil {
+		t.Fatalf("the signature does not verify: %v", err)
+	}
+	commit.Message = github.String("another message")
+	if _, err := openpgp.CheckArmoredDetachedSignature(keyring, strings.NewReader(commitPayload(commit)), strings.NewReader(signature), nil); err == nil {
+		t.Error("the signature verifies for a changed commit")
+	}
+}
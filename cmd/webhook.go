@@ -4,18 +4,29 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/google/go-github/v52/github"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 func init() {
 
 	webhookConfig.Flags().StringVar(&GithubOrgName, "githubOrgName", "", "name of the github organization")
-	webhookConfig.Flags().StringVar(&GithubRepo, "githubRepo", "", "name of the repo where the hook should be set up")
+	addWebhookTargetFlags(webhookConfig)
+	webhookConfig.Flags().StringVar(&WebhookURL, "url", "", "the URL the payloads will be delivered to")
+	webhookConfig.Flags().StringSliceVar(&WebhookEvents, "events", []string{"push"}, "comma separated list of events the hook is triggered for, '*' for all events")
+	addWebhookSecretFlags(webhookConfig)
+	webhookConfig.Flags().StringVar(&WebhookContentType, "content-type", "json", "the media type used to serialize the payloads, one of: json, form")
+	webhookConfig.Flags().BoolVar(&WebhookInsecureSSL, "insecure-ssl", false, "skip verification of the SSL certificate of the URL")
+	webhookConfig.Flags().BoolVar(&WebhookActive, "active", true, "whether the hook sends the payloads")
+	webhookConfig.Flags().StringVar(&WebhookPruneRegex, "prune-url-regex", "", "delete other hooks with URL matching the regex - optional")
 	webhookConfig.MarkFlagRequired("githubOrgName")
-	webhookConfig.MarkFlagRequired("githubRepo")
+	webhookConfig.MarkFlagRequired("url")
 
 	webhookConfig.Run = func(cmd *cobra.Command, args []string) {
 		if err := SetupWebhook(); err != nil {
@@ -24,66 +35,442 @@ func init() {
 	}
 
 	webhookList.Flags().StringVar(&GithubOrgName, "githubOrgName", "", "name of the github organization")
-	webhookList.Flags().StringVar(&GithubRepo, "githubRepo", "", "name of the repo where the hook should be set up")
+	addWebhookTargetFlags(webhookList)
 	webhookList.MarkFlagRequired("githubOrgName")
-	webhookList.MarkFlagRequired("githubRepo")
 
 	webhookList.Run = func(cmd *cobra.Command, args []string) {
 		if err := ListWebhooks(); err != nil {
 			log.Fatalf("error when listing webhooks: %v", err)
 		}
 	}
+
+	webhookDelete.Flags().StringVar(&GithubOrgName, "githubOrgName", "", "name of the github organization")
+	addWebhookTargetFlags(webhookDelete)
+	addWebhookIDFlags(webhookDelete)
+	webhookDelete.MarkFlagRequired("githubOrgName")
+
+	webhookDelete.Run = func(cmd *cobra.Command, args []string) {
+		if err := DeleteWebhook(); err != nil {
+			log.Fatalf("error when deleting webhook: %v", err)
+		}
+	}
+}
+
+// addWebhookTargetFlags registers the flags selecting between repository and organization hooks.
+func addWebhookTargetFlags(c *cobra.Command) {
+	c.Flags().StringVar(&GithubRepo, "githubRepo", "", "name of the repo the hook belongs to, required unless org-level is set")
+	c.Flags().BoolVar(&WebhookOrgLevel, "org-level", false, "manage the organization hooks instead of the repo hooks")
+}
+
+// addWebhookIDFlags registers the flags identifying a single hook.
+func addWebhookIDFlags(c *cobra.Command) {
+	c.Flags().Int64Var(&WebhookID, "id", 0, "the ID of the hook")
+	c.Flags().StringVar(&WebhookURL, "url", "", "select the hook by its URL when id is not specified")
+}
+
+// hookService abstracts over the repository and organization hooks,
+// github exposes both through separate but equivalent APIs.
+type hookService interface {
+	ListHooks(ctx context.Context, opts *github.ListOptions) ([]*github.Hook, *github.Response, error)
+	CreateHook(ctx context.Context, hook *github.Hook) (*github.Hook, *github.Response, error)
+	EditHook(ctx context.Context, id int64, hook *github.Hook) (*github.Hook, *github.Response, error)
+	DeleteHook(ctx context.Context, id int64) (*github.Response, error)
+	PingHook(ctx context.Context, id int64) (*github.Response, error)
+	ListHookDeliveries(ctx context.Context, id int64, opts *github.ListCursorOptions) ([]*github.HookDelivery, *github.Response, error)
+	GetHookDelivery(ctx context.Context, hookID, deliveryID int64) (*github.HookDelivery, *github.Response, error)
+	RedeliverHookDelivery(ctx context.Context, hookID, deliveryID int64) (*github.HookDelivery, *github.Response, error)
+}
+
+type repoHooks struct {
+	owner, repo string
+}
+
+func (h *repoHooks) ListHooks(ctx context.Context, opts *github.ListOptions) ([]*github.Hook, *github.Response, error) {
+	return GithubClient.Repositories.ListHooks(ctx, h.owner, h.repo, opts)
+}
+
+func (h *repoHooks) CreateHook(ctx context.Context, hook *github.Hook) (*github.Hook, *github.Response, error) {
+	return GithubClient.Repositories.CreateHook(ctx, h.owner, h.repo, hook)
+}
+
+func (h *repoHooks) EditHook(ctx context.Context, id int64, hook *github.Hook) (*github.Hook, *github.Response, error) {
+	return GithubClient.Repositories.EditHook(ctx, h.owner, h.repo, id, hook)
+}
+
+func (h *repoHooks) DeleteHook(ctx context.Context, id int64) (*github.Response, error) {
+	return GithubClient.Repositories.DeleteHook(ctx, h.owner, h.repo, id)
+}
+
+func (h *repoHooks) PingHook(ctx context.Context, id int64) (*github.Response, error) {
+	return GithubClient.Repositories.PingHook(ctx, h.owner, h.repo, id)
+}
+
+func (h *repoHooks) ListHookDeliveries(ctx context.Context, id int64, opts *github.ListCursorOptions) ([]*github.HookDelivery, *github.Response, error) {
+	return GithubClient.Repositories.ListHookDeliveries(ctx, h.owner, h.repo, id, opts)
+}
+
+func (h *repoHooks) GetHookDelivery(ctx context.Context, hookID, deliveryID int64) (*github.HookDelivery, *github.Response, error) {
+	return GithubClient.Repositories.GetHookDelivery(ctx, h.owner, h.repo, hookID, deliveryID)
+}
+
+func (h *repoHooks) RedeliverHookDelivery(ctx context.Context, hookID, deliveryID int64) (*github.HookDelivery, *github.Response, error) {
+	return GithubClient.Repositories.RedeliverHookDelivery(ctx, h.owner, h.repo, hookID, deliveryID)
+}
+
+type orgHooks struct {
+	org string
+}
+
+func (h *orgHooks) ListHooks(ctx context.Context, opts *github.ListOptions) ([]*github.Hook, *github.Response, error) {
+	return GithubClient.Organizations.ListHooks(ctx, h.org, opts)
+}
+
+func (h *orgHooks) CreateHook(ctx context.Context, hook *github.Hook) (*github.Hook, *github.Response, error) {
+	return GithubClient.Organizations.CreateHook(ctx, h.org, hook)
+}
+
+func (h *orgHooks) EditHook(ctx context.Context, id int64, hook *github.Hook) (*github.Hook, *github.Response, error) {
+	return GithubClient.Organizations.EditHook(ctx, h.org, id, hook)
+}
+
+func (h *orgHooks) DeleteHook(ctx context.Context, id int64) (*github.Response, error) {
+	return GithubClient.Organizations.DeleteHook(ctx, h.org, id)
+}
+
+func (h *orgHooks) PingHook(ctx context.Context, id int64) (*github.Response, error) {
+	return GithubClient.Organizations.PingHook(ctx, h.org, id)
+}
+
+func (h *orgHooks) ListHookDeliveries(ctx context.Context, id int64, opts *github.ListCursorOptions) ([]*github.HookDelivery, *github.Response, error) {
+	return GithubClient.Organizations.ListHookDeliveries(ctx, h.org, id, opts)
+}
+
+func (h *orgHooks) GetHookDelivery(ctx context.Context, hookID, deliveryID int64) (*github.HookDelivery, *github.Response, error) {
+	return GithubClient.Organizations.GetHookDelivery(ctx, h.org, hookID, deliveryID)
+}
+
+func (h *orgHooks) RedeliverHookDelivery(ctx context.Context, hookID, deliveryID int64) (*github.HookDelivery, *github.Response, error) {
+	return GithubClient.Organizations.RedeliverHookDelivery(ctx, h.org, hookID, deliveryID)
+}
+
+// webhookTarget returns the hooks of the repo, or of the organization when --org-level is set.
+func webhookTarget() (hookService, error) {
+	if WebhookOrgLevel {
+		return &orgHooks{org: GithubOrgName}, nil
+	}
+	if GithubRepo == "" {
+		return nil, fmt.Errorf("none of the parameters 'githubRepo' or 'org-level' specified")
+	}
+	return &repoHooks{owner: GithubOrgName, repo: GithubRepo}, nil
+}
+
+// listAllHooks returns all the hooks of the target, following the pagination.
+func listAllHooks(ctx context.Context, target hookService) ([]*github.Hook, error) {
+	var hooks []*github.Hook
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		list, res, err := target.ListHooks(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error when listing webhooks: %v", err)
+		}
+		hooks = append(hooks, list...)
+		if res.NextPage == 0 {
+			break
+		}
+		opts.Page = res.NextPage
+	}
+	return hooks, nil
+}
+
+// WebhookInfo is a single hook listed by webhook-list.
+type WebhookInfo struct {
+	ID             int64     `json:"id" yaml:"id"`
+	URL            string    `json:"url" yaml:"url"`
+	Events         []string  `json:"events" yaml:"events"`
+	Active         bool      `json:"active" yaml:"active"`
+	ContentType    string    `json:"contentType" yaml:"contentType"`
+	CreatedAt      time.Time `json:"createdAt" yaml:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt" yaml:"updatedAt"`
+	LastStatus     string    `json:"lastStatus" yaml:"lastStatus"`
+	LastStatusCode int       `json:"lastStatusCode" yaml:"lastStatusCode"`
 }
 
 func ListWebhooks() error {
-	hooks, _, err := GithubClient.Repositories.ListHooks(context.Background(), GithubOrgName, GithubRepo, &github.ListOptions{})
+	target, err := webhookTarget()
+	if err != nil {
+		return err
+	}
+	hooks, err := listAllHooks(context.Background(), target)
 	if err != nil {
-		return fmt.Errorf("error when listing webhooks: %+v", err)
+		return err
 	}
 
-	url := hooks[0].Config["url"].(string)
-	log.Println(url)
-	return nil
+	infos := []*WebhookInfo{}
+	for _, hook := range hooks {
+		infos = append(infos, &WebhookInfo{
+			ID:             hook.GetID(),
+			URL:            hookConfigString(hook, "url"),
+			Events:         hook.Events,
+			Active:         hook.GetActive(),
+			ContentType:    hookConfigString(hook, "content_type"),
+			CreatedAt:      hook.GetCreatedAt().Time,
+			UpdatedAt:      hook.GetUpdatedAt().Time,
+			LastStatus:     hookLastStatus(hook),
+			LastStatusCode: hookLastStatusCode(hook),
+		})
+	}
+
+	return writeOutput(infos, func() {
+		if len(infos) == 0 {
+			fmt.Println("no webhooks found")
+		}
+		for _, h := range infos {
+			fmt.Printf("%d\t%s\t%s\tactive=%t\t%s\t%s\t%s %d\n", h.ID, h.URL, strings.Join(h.Events, ","), h.Active, h.ContentType, h.UpdatedAt.Format(time.RFC3339), h.LastStatus, h.LastStatusCode)
+		}
+	})
+}
+
+// hookLastStatus returns the status of the last delivery, e.g. "active" or "unused".
+func hookLastStatus(hook *github.Hook) string {
+	status, _ := hook.LastResponse["status"].(string)
+	return status
 }
 
+// hookLastStatusCode returns the HTTP status of the last delivery, 0 when there was none.
+func hookLastStatusCode(hook *github.Hook) int {
+	// numbers are decoded as float64 into the untyped map
+	code, _ := hook.LastResponse["code"].(float64)
+	return int(code)
+}
+
+const (
+	WebhookCreated   = "created"
+	WebhookUpdated   = "updated"
+	WebhookUnchanged = "unchanged"
+	WebhookDeleted   = "deleted"
+)
+
+// WebhookResult describes what webhook-config did with a hook.
+type WebhookResult struct {
+	ID     int64  `json:"id" yaml:"id"`
+	URL    string `json:"url" yaml:"url"`
+	Action string `json:"action" yaml:"action"`
+}
+
+// SetupWebhook makes sure there is exactly one hook for the URL with the
+// configuration given via flags, further hooks with the same URL are deleted.
+// Hooks with other URLs are left untouched unless they match --prune-url-regex.
 func SetupWebhook() error {
 
 	ctx := context.Background()
 
-	hooks, _, err := GithubClient.Repositories.ListHooks(ctx, GithubOrgName, GithubRepo, &github.ListOptions{})
+	var pruneRegex *regexp.Regexp
+	if WebhookPruneRegex != "" {
+		var err error
+		if pruneRegex, err = regexp.Compile(WebhookPruneRegex); err != nil {
+			return fmt.Errorf("problem with regexp: %+v", err)
+		}
+	}
+
+	desired, err := webhookFromFlags()
+	if err != nil {
+		return err
+	}
+
+	target, err := webhookTarget()
+	if err != nil {
+		return err
+	}
+	hooks, err := listAllHooks(ctx, target)
+	if err != nil {
+		return err
+	}
+
+	var results []*WebhookResult
+	var existing *github.Hook
+	for _, hook := range hooks {
+		url := hookConfigString(hook, "url")
+		switch {
+		case url == WebhookURL && existing == nil:
+			existing = hook
+			continue
+		case url == WebhookURL:
+			log.Printf("hook %d duplicates the hook %d for url %s, deleting...", hook.GetID(), existing.GetID(), url)
+		case pruneRegex != nil && pruneRegex.MatchString(url):
+			log.Printf("hook %d with url %s matches the prune regex, deleting...", hook.GetID(), url)
+		default:
+			continue
+		}
+		_, err := target.DeleteHook(ctx, hook.GetID())
+		if err != nil {
+			return fmt.Errorf("error when deleting webhook: %v", err)
+		}
+		results = append(results, &WebhookResult{ID: hook.GetID(), URL: url, Action: WebhookDeleted})
+	}
+
+	switch {
+	case existing == nil:
+		createdHook, _, err := target.CreateHook(ctx, desired)
+		if err != nil {
+			return fmt.Errorf("error when creating webhook: %v", err)
+		}
+		results = append(results, &WebhookResult{ID: createdHook.GetID(), URL: WebhookURL, Action: WebhookCreated})
+	case hookUpToDate(existing, desired):
+		results = append(results, &WebhookResult{ID: existing.GetID(), URL: WebhookURL, Action: WebhookUnchanged})
+	case hookConfigString(existing, "secret") != "" && hookConfigString(desired, "secret") == "":
+		// the update would remove the secret and the payloads would no longer be signed
+		return fmt.Errorf("webhook %d has a secret, specify 'secret' or 'secret-file' to update it", existing.GetID())
+	default:
+		_, _, err := target.EditHook(ctx, existing.GetID(), desired)
+		if err != nil {
+			return fmt.Errorf("error when updating webhook: %v", err)
+		}
+		results = append(results, &WebhookResult{ID: existing.GetID(), URL: WebhookURL, Action: WebhookUpdated})
+	}
+
+	return writeOutput(results, func() {
+		for _, r := range results {
+			fmt.Printf("webhook %d %s: %s\n", r.ID, r.Action, r.URL)
+		}
+	})
+}
+
+func DeleteWebhook() error {
+
+	ctx := context.Background()
+
+	target, err := webhookTarget()
+	if err != nil {
+		return err
+	}
+
+	id, err := resolveHookID(ctx, target)
 	if err != nil {
-		return fmt.Errorf("error when listing webhooks: %v", err)
+		return err
+	}
+
+	if _, err := target.DeleteHook(ctx, id); err != nil {
+		return fmt.Errorf("error when deleting webhook: %v", err)
 	}
 
+	result := &WebhookResult{ID: id, URL: WebhookURL, Action: WebhookDeleted}
+	return writeOutput(result, func() {
+		fmt.Printf("webhook %d deleted\n", result.ID)
+	})
+}
+
+// resolveHookID returns the hook given via --id, or looks it up by --url.
+func resolveHookID(ctx context.Context, target hookService) (int64, error) {
+	if WebhookID != 0 {
+		return WebhookID, nil
+	}
+	if WebhookURL == "" {
+		return 0, fmt.Errorf("none of the parameters 'id' or 'url' specified")
+	}
+	hooks, err := listAllHooks(ctx, target)
+	if err != nil {
+		return 0, err
+	}
 	for _, hook := range hooks {
-		createdAt := hook.GetCreatedAt()
+		if hookConfigString(hook, "url") == WebhookURL {
+			return hook.GetID(), nil
+		}
+	}
+	return 0, fmt.Errorf("no webhook with url %s found", WebhookURL)
+}
 
-		if createdAt.Before(time.Now().Add(time.Hour * -24)) {
-			log.Printf("hook %s is older than a day, deleting...", hook.GetURL())
-			log.Println(hook.Events)
-			// TODO add delete function
-			_, err := GithubClient.Repositories.DeleteHook(ctx, GithubOrgName, GithubRepo, *hook.ID)
-			if err != nil {
-				return fmt.Errorf("error when deleting webhook: %v", err)
-			}
+// hookUpToDate reports whether the existing hook already has the desired
+// configuration. Github only returns a masked secret, so a hook is always
+// updated when a secret is given, and a hook with a secret is never up to
+// date with a configuration without one.
+func hookUpToDate(existing, desired *github.Hook) bool {
+	if _, ok := desired.Config["secret"]; ok {
+		return false
+	}
+	if hookConfigString(existing, "secret") != "" {
+		return false
+	}
+	if existing.GetActive() != desired.GetActive() {
+		return false
+	}
+	for _, key := range []string{"content_type", "insecure_ssl"} {
+		if hookConfigString(existing, key) != fmt.Sprint(desired.Config[key]) {
+			return false
 		}
 	}
+	if len(existing.Events) != len(desired.Events) {
+		return false
+	}
+	events := map[string]bool{}
+	for _, e := range existing.Events {
+		events[e] = true
+	}
+	for _, e := range desired.Events {
+		if !events[e] {
+			return false
+		}
+	}
+	return true
+}
+
+// hookConfigString returns the hook config value as a string, github returns
+// some of the values like insecure_ssl either as strings or numbers.
+func hookConfigString(hook *github.Hook, key string) string {
+	v, ok := hook.Config[key]
+	if !ok || v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// addWebhookSecretFlags registers the flags the webhook secret can be given with.
+func addWebhookSecretFlags(c *cobra.Command) {
+	c.Flags().StringVar(&WebhookSecret, "secret", "", fmt.Sprintf("the secret used to sign the payloads. Can be set via the %s env var.", strings.ToUpper(WebhookSecKey)))
+	c.Flags().StringVar(&WebhookSecretFile, "secret-file", "", "path to a file with the secret used to sign the payloads")
+}
 
-	newHookTemplate := &github.Hook{
-		Active: github.Bool(true),
-		Events: []string{"push"},
-		Config: map[string]interface{}{
-			"content_type": "json",
-			"insecure_ssl": 0,
-			"url":          "http://asdfasdfasjflakadlfkadjsflkjadasdfslkfjasdlkfjasdkfdjladsk.com",
-		},
+// webhookSecret returns the secret given via --secret, --secret-file or
+// the env var, in this order. An empty secret means unsigned payloads.
+func webhookSecret() (string, error) {
+	if WebhookSecret != "" {
+		return WebhookSecret, nil
 	}
-	createdHook, _, err := GithubClient.Repositories.CreateHook(ctx, GithubOrgName, GithubRepo, newHookTemplate)
+	if WebhookSecretFile != "" {
+		data, err := os.ReadFile(WebhookSecretFile)
+		if err != nil {
+			return "", fmt.Errorf("error when reading webhook secret: %v", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	return viper.GetString(WebhookSecKey), nil
+}
+
+// webhookFromFlags builds the hook configuration out of the webhook-config flags.
+func webhookFromFlags() (*github.Hook, error) {
+	if WebhookContentType != "json" && WebhookContentType != "form" {
+		return nil, fmt.Errorf("unsupported content type '%s', use one of: json, form", WebhookContentType)
+	}
+	secret, err := webhookSecret()
 	if err != nil {
-		return fmt.Errorf("error when creating webhook: %v", err)
+		return nil, err
 	}
 
-	log.Printf("webhook created: %s", *createdHook.URL)
+	insecureSSL := "0"
+	if WebhookInsecureSSL {
+		insecureSSL = "1"
+	}
+	config := map[string]interface{}{
+		"url":          WebhookURL,
+		"content_type": WebhookContentType,
+		"insecure_ssl": insecureSSL,
+	}
+	if secret != "" {
+		config["secret"] = secret
+	}
 
-	return nil
+	return &github.Hook{
+		Active: github.Bool(WebhookActive),
+		Events: WebhookEvents,
+		Config: config,
+	}, nil
 }
@@ -0,0 +1,416 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/google/go-github/v52/github"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+
+	for _, c := range []*cobra.Command{webhookDeliveries, webhookDeliveryGet, webhookRedeliver} {
+		c.Flags().StringVar(&GithubOrgName, "githubOrgName", "", "name of the github organization")
+		addWebhookTargetFlags(c)
+		addWebhookIDFlags(c)
+		c.MarkFlagRequired("githubOrgName")
+	}
+
+	webhookDeliveries.Flags().DurationVar(&WebhookListSince, "since", 0, "list only deliveries made within this duration, e.g. 2h")
+	webhookDeliveries.Flags().IntVar(&WebhookLimit, "limit", 30, "maximum number of deliveries to list")
+	webhookDeliveries.Flags().BoolVar(&WebhookFailedOnly, "failed", false, "list only deliveries which did not get a 2xx response")
+
+	webhookDeliveries.Run = func(cmd *cobra.Command, args []string) {
+		if err := ListWebhookDeliveries(); err != nil {
+			log.Fatalf("error when listing webhook deliveries: %v", err)
+		}
+	}
+
+	webhookDeliveryGet.Flags().Int64Var(&WebhookDeliveryID, "delivery-id", 0, "the ID of the delivery")
+	webhookDeliveryGet.MarkFlagRequired("delivery-id")
+
+	webhookDeliveryGet.Run = func(cmd *cobra.Command, args []string) {
+		if err := GetWebhookDelivery(); err != nil {
+			log.Fatalf("error when getting webhook delivery: %v", err)
+		}
+	}
+
+	webhookTest.Flags().StringVar(&GithubOrgName, "githubOrgName", "", "name of the github organization")
+	addWebhookTargetFlags(webhookTest)
+	addWebhookIDFlags(webhookTest)
+	webhookTest.Flags().DurationVar(&WebhookTestTimeout, "timeout", time.Minute, "how long to wait for the ping delivery")
+	webhookTest.Flags().DurationVar(&WebhookTestInterval, "interval", 2*time.Second, "how often to poll")
+	webhookTest.MarkFlagRequired("githubOrgName")
+
+	webhookTest.Run = func(cmd *cobra.Command, args []string) {
+		if err := TestWebhook(); err != nil {
+			log.Fatalf("error when testing webhook: %v", err)
+		}
+	}
+
+	webhookRedeliver.Flags().DurationVar(&WebhookRetrySince, "since", time.Hour, "redeliver failed deliveries made within this duration")
+	webhookRedeliver.Flags().DurationVar(&WebhookUntil, "until", 0, "skip deliveries made within this duration, e.g. 5m to leave in-flight retries alone")
+	webhookRedeliver.Flags().BoolVar(&DryRun, "dryRun", false, "only print the deliveries that would be redelivered")
+
+	webhookRedeliver.Run = func(cmd *cobra.Command, args []string) {
+		if err := RedeliverWebhooks(); err != nil {
+			log.Fatalf("error when redelivering webhooks: %v", err)
+		}
+	}
+}
+
+// WebhookDelivery is a single delivery of a hook.
+type WebhookDelivery struct {
+	ID          int64     `json:"id" yaml:"id"`
+	GUID        string    `json:"guid" yaml:"guid"`
+	Event       string    `json:"event" yaml:"event"`
+	Action      string    `json:"action,omitempty" yaml:"action,omitempty"`
+	StatusCode  int       `json:"statusCode" yaml:"statusCode"`
+	Status      string    `json:"status" yaml:"status"`
+	Duration    float64   `json:"duration" yaml:"duration"`
+	Redelivery  bool      `json:"redelivery" yaml:"redelivery"`
+	DeliveredAt time.Time `json:"deliveredAt" yaml:"deliveredAt"`
+}
+
+// WebhookDeliveryDetail is a delivery together with the request sent by
+// github and the response returned by the endpoint.
+type WebhookDeliveryDetail struct {
+	WebhookDelivery `yaml:",inline"`
+	Request         *WebhookMessage `json:"request" yaml:"request"`
+	Response        *WebhookMessage `json:"response" yaml:"response"`
+}
+
+// WebhookMessage holds the headers and the decoded payload of a request or response.
+type WebhookMessage struct {
+	Headers map[string]string `json:"headers" yaml:"headers"`
+	Payload interface{}       `json:"payload" yaml:"payload"`
+}
+
+func newWebhookDelivery(d *github.HookDelivery) *WebhookDelivery {
+	var duration float64
+	if d.Duration != nil {
+		duration = *d.Duration
+	}
+	return &WebhookDelivery{
+		ID:          d.GetID(),
+		GUID:        d.GetGUID(),
+		Event:       d.GetEvent(),
+		Action:      d.GetAction(),
+		StatusCode:  d.GetStatusCode(),
+		Status:      d.GetStatus(),
+		Duration:    duration,
+		Redelivery:  d.GetRedelivery(),
+		DeliveredAt: d.GetDeliveredAt().Time,
+	}
+}
+
+// deliverySucceeded reports whether the endpoint answered with 2xx, a status
+// code of 0 means github could not connect or timed out.
+func deliverySucceeded(d *github.HookDelivery) bool {
+	return d.GetStatusCode() >= 200 && d.GetStatusCode() < 300
+}
+
+// listDeliveries returns the deliveries of the hook made after since, newest
+// first. A zero since lists all deliveries, a limit of 0 means no limit.
+func listDeliveries(ctx context.Context, target hookService, hookID int64, since time.Time, limit int) ([]*github.HookDelivery, error) {
+	var deliveries []*github.HookDelivery
+	opts := &github.ListCursorOptions{PerPage: 100}
+	for {
+		list, res, err := target.ListHookDeliveries(ctx, hookID, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error when listing deliveries of webhook %d: %v", hookID, err)
+		}
+		for _, d := range list {
+			if d.GetDeliveredAt().Time.Before(since) {
+				return deliveries, nil
+			}
+			deliveries = append(deliveries, d)
+			if limit > 0 && len(deliveries) == limit {
+				return deliveries, nil
+			}
+		}
+		if res.Cursor == "" {
+			return deliveries, nil
+		}
+		opts.Cursor = res.Cursor
+	}
+}
+
+func ListWebhookDeliveries() error {
+
+	ctx := context.Background()
+
+	target, err := webhookTarget()
+	if err != nil {
+		return err
+	}
+	hookID, err := resolveHookID(ctx, target)
+	if err != nil {
+		return err
+	}
+
+	var since time.Time
+	if WebhookListSince > 0 {
+		since = time.Now().Add(-WebhookListSince)
+	}
+	limit := WebhookLimit
+	if WebhookFailedOnly {
+		// the limit applies to the failed deliveries, filtered below
+		limit = 0
+	}
+	list, err := listDeliveries(ctx, target, hookID, since, limit)
+	if err != nil {
+		return err
+	}
+
+	deliveries := []*WebhookDelivery{}
+	for _, d := range list {
+		if WebhookFailedOnly && deliverySucceeded(d) {
+			continue
+		}
+		if WebhookLimit > 0 && len(deliveries) == WebhookLimit {
+			break
+		}
+		deliveries = append(deliveries, newWebhookDelivery(d))
+	}
+
+	return writeOutput(deliveries, func() {
+		if len(deliveries) == 0 {
+			fmt.Println("no deliveries found")
+		}
+		for _, d := range deliveries {
+			event := d.Event
+			if d.Action != "" {
+				event = fmt.Sprintf("%s.%s", d.Event, d.Action)
+			}
+			fmt.Printf("%d\t%s\t%s\t%d\t%.2fs\t%s\t%s\n", d.ID, d.DeliveredAt.Format(time.RFC3339), event, d.StatusCode, d.Duration, d.Status, d.GUID)
+		}
+	})
+}
+
+func GetWebhookDelivery() error {
+
+	ctx := context.Background()
+
+	target, err := webhookTarget()
+	if err != nil {
+		return err
+	}
+	hookID, err := resolveHookID(ctx, target)
+	if err != nil {
+		return err
+	}
+
+	d, _, err := target.GetHookDelivery(ctx, hookID, WebhookDeliveryID)
+	if err != nil {
+		return fmt.Errorf("error when getting delivery %d of webhook %d: %v", WebhookDeliveryID, hookID, err)
+	}
+
+	detail := &WebhookDeliveryDetail{WebhookDelivery: *newWebhookDelivery(d)}
+	if d.Request != nil {
+		detail.Request = &WebhookMessage{Headers: d.Request.Headers, Payload: decodePayload(d.Request.RawPayload)}
+	}
+	if d.Response != nil {
+		detail.Response = &WebhookMessage{Headers: d.Response.Headers, Payload: decodePayload(d.Response.RawPayload)}
+	}
+
+	return writeOutput(detail, func() {
+		fmt.Printf("delivery %d (%s) of %s at %s: %d %s in %.2fs\n", detail.ID, detail.GUID, detail.Event, detail.DeliveredAt.Format(time.RFC3339), detail.StatusCode, detail.Status, detail.Duration)
+		printWebhookMessage("request", detail.Request)
+		printWebhookMessage("response", detail.Response)
+	})
+}
+
+// decodePayload decodes the raw JSON payload so it can be printed in any of
+// the output formats. The response payload is usually a plain JSON string.
+func decodePayload(raw *json.RawMessage) interface{} {
+	if raw == nil {
+		return nil
+	}
+	var payload interface{}
+	if err := json.Unmarshal(*raw, &payload); err != nil {
+		return string(*raw)
+	}
+	return payload
+}
+
+func printWebhookMessage(name string, m *WebhookMessage) {
+	fmt.Printf("\n--- %s\n", name)
+	if m == nil {
+		return
+	}
+	var keys []string
+	for k := range m.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Printf("%s: %s\n", k, m.Headers[k])
+	}
+	fmt.Println()
+	if s, ok := m.Payload.(string); ok {
+		fmt.Println(s)
+		return
+	}
+	out, err := json.MarshalIndent(m.Payload, "", "  ")
+	if err != nil {
+		fmt.Println(m.Payload)
+		return
+	}
+	fmt.Println(string(out))
+}
+
+// TestWebhook pings the hook and waits for the ping delivery to show up in
+// the deliveries, it fails unless the endpoint answered with 2xx.
+func TestWebhook() error {
+
+	ctx := context.Background()
+
+	target, err := webhookTarget()
+	if err != nil {
+		return err
+	}
+	hookID, err := resolveHookID(ctx, target)
+	if err != nil {
+		return err
+	}
+
+	// the clocks of github and the local machine may differ, so the ping is
+	// recognized as the first ping delivery not listed before. New deliveries
+	// are listed first, so looking at the latest page is enough.
+	before, err := listDeliveries(ctx, target, hookID, time.Time{}, 100)
+	if err != nil {
+		return err
+	}
+	known := map[int64]bool{}
+	for _, d := range before {
+		known[d.GetID()] = true
+	}
+
+	if _, err := target.PingHook(ctx, hookID); err != nil {
+		return fmt.Errorf("error when pinging webhook %d: %v", hookID, err)
+	}
+
+	deadline := time.Now().Add(WebhookTestTimeout)
+	log.Printf("pinged webhook %d, waiting for the delivery", hookID)
+	for {
+		list, err := listDeliveries(ctx, target, hookID, time.Time{}, 100)
+		if err != nil {
+			return err
+		}
+		for _, d := range list {
+			if known[d.GetID()] || d.GetEvent() != "ping" {
+				continue
+			}
+			delivery := newWebhookDelivery(d)
+			err := writeOutput(delivery, func() {
+				fmt.Printf("ping delivery %d: %d %s in %.2fs\n", delivery.ID, delivery.StatusCode, delivery.Status, delivery.Duration)
+			})
+			if err != nil {
+				return err
+			}
+			if !deliverySucceeded(d) {
+				return fmt.Errorf("webhook %d endpoint responded with %d: %s", hookID, delivery.StatusCode, delivery.Status)
+			}
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out after %s waiting for the ping delivery of webhook %d", WebhookTestTimeout, hookID)
+		}
+		time.Sleep(WebhookTestInterval)
+	}
+}
+
+// WebhookRedelivery is a failed delivery handled by webhook-redeliver.
+type WebhookRedelivery struct {
+	WebhookDelivery `yaml:",inline"`
+	RedeliveryID    int64  `json:"redeliveryId,omitempty" yaml:"redeliveryId,omitempty"`
+	Error           string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// RedeliverWebhooks redelivers the deliveries of the time window which did not
+// get a 2xx response. Github keeps the GUID on redelivery, so a delivery is
+// skipped when any attempt with the same GUID has already succeeded.
+func RedeliverWebhooks() error {
+
+	ctx := context.Background()
+
+	target, err := webhookTarget()
+	if err != nil {
+		return err
+	}
+	hookID, err := resolveHookID(ctx, target)
+	if err != nil {
+		return err
+	}
+
+	since := time.Now().Add(-WebhookRetrySince)
+	until := time.Now().Add(-WebhookUntil)
+	list, err := listDeliveries(ctx, target, hookID, since, 0)
+	if err != nil {
+		return err
+	}
+
+	succeeded := map[string]bool{}
+	for _, d := range list {
+		if deliverySucceeded(d) {
+			succeeded[d.GetGUID()] = true
+		}
+	}
+
+	// deliveries are listed newest first, only the latest attempt of a GUID is redelivered
+	seen := map[string]bool{}
+	redeliveries := []*WebhookRedelivery{}
+	var failed int
+	for _, d := range list {
+		if succeeded[d.GetGUID()] || seen[d.GetGUID()] || d.GetDeliveredAt().Time.After(until) {
+			continue
+		}
+		seen[d.GetGUID()] = true
+
+		r := &WebhookRedelivery{WebhookDelivery: *newWebhookDelivery(d)}
+		redeliveries = append(redeliveries, r)
+		if DryRun {
+			continue
+		}
+		redelivery, _, err := target.RedeliverHookDelivery(ctx, hookID, d.GetID())
+		// the redelivery is scheduled in the background and github responds with 202
+		var accepted *github.AcceptedError
+		if err != nil && !errors.As(err, &accepted) {
+			r.Error = err.Error()
+			failed++
+			continue
+		}
+		r.RedeliveryID = redelivery.GetID()
+	}
+
+	err = writeOutput(redeliveries, func() {
+		if len(redeliveries) == 0 {
+			fmt.Println("no failed deliveries found")
+		}
+		for _, r := range redeliveries {
+			switch {
+			case DryRun:
+				fmt.Printf("would redeliver %d\t%s\t%s\t%d\n", r.ID, r.DeliveredAt.Format(time.RFC3339), r.Event, r.StatusCode)
+			case r.Error != "":
+				fmt.Printf("failed to redeliver %d\t%s\t%s\t%s\n", r.ID, r.DeliveredAt.Format(time.RFC3339), r.Event, r.Error)
+			default:
+				fmt.Printf("redelivered %d\t%s\t%s\t%d\n", r.ID, r.DeliveredAt.Format(time.RFC3339), r.Event, r.StatusCode)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d deliveries could not be redelivered", failed, len(redeliveries))
+	}
+	return nil
+}
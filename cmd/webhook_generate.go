@@ -0,0 +1,355 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v52/github"
+	"github.com/spf13/cobra"
+)
+
+// generatedActions are the supported actions of every event webhook-generate
+// can build, the first one is the default.
+var generatedActions = map[string][]string{
+	"push":          {""},
+	"pull_request":  {"opened", "synchronize", "closed"},
+	"issue_comment": {"created", "edited", "deleted"},
+	"check_run":     {"completed", "created", "rerequested"},
+}
+
+func init() {
+
+	webhookGenerate.Flags().StringVar(&WebhookEventName, "event", "", "the event to generate, one of: push, pull_request, issue_comment, check_run")
+	webhookGenerate.Flags().StringVar(&WebhookAction, "action", "", "the action of the event, defaults to the most common one, e.g. opened for pull_request")
+	webhookGenerate.Flags().StringVar(&GithubOrgName, "githubOrgName", "", "name of the github organization owning the repo")
+	webhookGenerate.Flags().StringVar(&GithubRepo, "githubRepo", "", "name of the repo the event belongs to")
+	webhookGenerate.Flags().StringVar(&GithubBranchName, "branchName", "", "the pushed branch, or the head branch of the PR, defaults to the base branch for push and to 'feature' otherwise")
+	webhookGenerate.Flags().StringVar(&WebhookBaseBranch, "baseBranchName", "main", "the base branch of the PR")
+	webhookGenerate.Flags().StringVar(&CommitSHA, "sha", "", "the head commit SHA, random when not specified")
+	webhookGenerate.Flags().IntVar(&WebhookPRNumber, "prNumber", 1, "the number of the PR")
+	webhookGenerate.Flags().StringVar(&WebhookComment, "body", "/retest", "the body of the comment")
+	webhookGenerate.Flags().StringVar(&WebhookSender, "sender", "octocat", "the login of the user triggering the event")
+	webhookGenerate.Flags().BoolVar(&WebhookMerged, "merged", true, "whether a closed PR was merged")
+	webhookGenerate.Flags().StringVar(&WebhookCheckName, "check-name", "build", "the name of the check run")
+	webhookGenerate.Flags().StringVar(&WebhookConclusion, "conclusion", "success", "the conclusion of a completed check run")
+	webhookGenerate.Flags().StringVar(&WebhookOutDir, "out-dir", "", "write the payload to this directory, named so webhook-replay picks up the event")
+	webhookGenerate.Flags().StringVar(&WebhookURL, "url", "", "send the payload signed to this URL instead of printing it")
+	addWebhookSecretFlags(webhookGenerate)
+	webhookGenerate.MarkFlagRequired("event")
+	webhookGenerate.MarkFlagRequired("githubOrgName")
+	webhookGenerate.MarkFlagRequired("githubRepo")
+
+	webhookGenerate.Run = func(cmd *cobra.Command, args []string) {
+		if err := GenerateWebhook(); err != nil {
+			log.Fatalf("error when generating webhook: %v", err)
+		}
+	}
+}
+
+func GenerateWebhook() error {
+
+	actions, ok := generatedActions[WebhookEventName]
+	if !ok {
+		return fmt.Errorf("unsupported event '%s', use one of: push, pull_request, issue_comment, check_run", WebhookEventName)
+	}
+	action := WebhookAction
+	if action == "" {
+		action = actions[0]
+	}
+	supported := false
+	for _, a := range actions {
+		supported = supported || a == action
+	}
+	if !supported {
+		return fmt.Errorf("unsupported action '%s' for %s, use one of: %s", action, WebhookEventName, strings.Join(actions, ", "))
+	}
+
+	if GithubBranchName == "" {
+		GithubBranchName = "feature"
+		if WebhookEventName == "push" {
+			GithubBranchName = WebhookBaseBranch
+		}
+	}
+
+	event := newEventGenerator(action).generate(WebhookEventName)
+	payload, err := json.MarshalIndent(event, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error when encoding payload: %v", err)
+	}
+
+	switch {
+	case WebhookURL != "":
+		secret, err := webhookSecret()
+		if err != nil {
+			return err
+		}
+		result := sendWebhook(&http.Client{Timeout: 30 * time.Second}, WebhookURL, WebhookEventName, payload, secret)
+		err = writeOutput(result, func() {
+			printWebhookSendResults([]*WebhookSendResult{result})
+		})
+		if err != nil {
+			return err
+		}
+		if result.Error != "" {
+			return fmt.Errorf("payload was not accepted by %s: %s", WebhookURL, result.Error)
+		}
+		return nil
+	case WebhookOutDir != "":
+		if err := os.MkdirAll(WebhookOutDir, 0755); err != nil {
+			return fmt.Errorf("error when creating output directory: %v", err)
+		}
+		path, err := recordPayload(WebhookOutDir, &WebhookEvent{Time: time.Now().UTC(), Event: WebhookEventName, Delivery: newDeliveryID()}, payload)
+		if err != nil {
+			return err
+		}
+		log.Printf("%s payload written to %s", WebhookEventName, path)
+		return nil
+	default:
+		fmt.Println(string(payload))
+		return nil
+	}
+}
+
+// eventGenerator builds the payloads out of the webhook-generate flags, the
+// objects shared between the events are created once so they are consistent.
+type eventGenerator struct {
+	action string
+	now    *github.Timestamp
+	sha    string
+	owner  *github.User
+	sender *github.User
+	repo   *github.Repository
+	pr     *github.PullRequest
+}
+
+func newEventGenerator(action string) *eventGenerator {
+	g := &eventGenerator{
+		action: action,
+		now:    &github.Timestamp{Time: time.Now().UTC().Truncate(time.Second)},
+		sha:    CommitSHA,
+	}
+	if g.sha == "" {
+		g.sha = randomSHA()
+	}
+	g.owner = &github.User{
+		ID:      github.Int64(1),
+		Login:   github.String(GithubOrgName),
+		Type:    github.String("Organization"),
+		HTMLURL: github.String(fmt.Sprintf("https://github.com/%s", GithubOrgName)),
+	}
+	g.sender = &github.User{
+		ID:      github.Int64(2),
+		Login:   github.String(WebhookSender),
+		Type:    github.String("User"),
+		HTMLURL: github.String(fmt.Sprintf("https://github.com/%s", WebhookSender)),
+	}
+	fullName := fmt.Sprintf("%s/%s", GithubOrgName, GithubRepo)
+	g.repo = &github.Repository{
+		ID:            github.Int64(100),
+		Name:          github.String(GithubRepo),
+		FullName:      github.String(fullName),
+		Owner:         g.owner,
+		Private:       github.Bool(false),
+		DefaultBranch: github.String(WebhookBaseBranch),
+		HTMLURL:       github.String(fmt.Sprintf("https://github.com/%s", fullName)),
+		CloneURL:      github.String(fmt.Sprintf("https://github.com/%s.git", fullName)),
+		SSHURL:        github.String(fmt.Sprintf("git@github.com:%s.git", fullName)),
+	}
+	g.pr = g.pullRequest()
+	return g
+}
+
+func (g *eventGenerator) generate(event string) interface{} {
+	switch event {
+	case "push":
+		return g.pushEvent()
+	case "pull_request":
+		return g.pullRequestEvent()
+	case "issue_comment":
+		return g.issueCommentEvent()
+	default:
+		return g.checkRunEvent()
+	}
+}
+
+func (g *eventGenerator) pushEvent() *github.PushEvent {
+	before := randomSHA()
+	commit := &github.HeadCommit{
+		ID:        github.String(g.sha),
+		TreeID:    github.String(randomSHA()),
+		Message:   github.String("Generated commit"),
+		Timestamp: g.now,
+		URL:       github.String(fmt.Sprintf("%s/commit/%s", g.repo.GetHTMLURL(), g.sha)),
+		Author:    &github.CommitAuthor{Name: github.String(WebhookSender), Email: github.String(WebhookSender + "@users.noreply.github.com"), Login: github.String(WebhookSender)},
+		Committer: &github.CommitAuthor{Name: github.String("GitHub"), Email: github.String("noreply@github.com")},
+		Distinct:  github.Bool(true),
+	}
+	return &github.PushEvent{
+		Ref:        github.String("refs/heads/" + GithubBranchName),
+		Before:     github.String(before),
+		After:      github.String(g.sha),
+		Created:    github.Bool(false),
+		Deleted:    github.Bool(false),
+		Forced:     github.Bool(false),
+		Compare:    github.String(fmt.Sprintf("%s/compare/%s...%s", g.repo.GetHTMLURL(), before, g.sha)),
+		Commits:    []*github.HeadCommit{commit},
+		HeadCommit: commit,
+		Repo: &github.PushEventRepository{
+			ID:            g.repo.ID,
+			Name:          g.repo.Name,
+			FullName:      g.repo.FullName,
+			Owner:         g.owner,
+			Private:       g.repo.Private,
+			DefaultBranch: g.repo.DefaultBranch,
+			MasterBranch:  g.repo.DefaultBranch,
+			Organization:  github.String(GithubOrgName),
+			HTMLURL:       g.repo.HTMLURL,
+			CloneURL:      g.repo.CloneURL,
+			SSHURL:        g.repo.SSHURL,
+		},
+		Pusher:       &github.User{Name: github.String(WebhookSender), Email: github.String(WebhookSender + "@users.noreply.github.com")},
+		Sender:       g.sender,
+		Organization: g.organization(),
+	}
+}
+
+func (g *eventGenerator) pullRequest() *github.PullRequest {
+	pr := &github.PullRequest{
+		ID:        github.Int64(1000 + int64(WebhookPRNumber)),
+		Number:    github.Int(WebhookPRNumber),
+		State:     github.String("open"),
+		Title:     github.String(fmt.Sprintf("Generated PR from %s", GithubBranchName)),
+		Body:      github.String("Generated by ggh webhook-generate"),
+		User:      g.sender,
+		Draft:     github.Bool(false),
+		Merged:    github.Bool(false),
+		CreatedAt: g.now,
+		UpdatedAt: g.now,
+		HTMLURL:   github.String(fmt.Sprintf("%s/pull/%d", g.repo.GetHTMLURL(), WebhookPRNumber)),
+		Head: &github.PullRequestBranch{
+			Label: github.String(fmt.Sprintf("%s:%s", GithubOrgName, GithubBranchName)),
+			Ref:   github.String(GithubBranchName),
+			SHA:   github.String(g.sha),
+			Repo:  g.repo,
+			User:  g.owner,
+		},
+		Base: &github.PullRequestBranch{
+			Label: github.String(fmt.Sprintf("%s:%s", GithubOrgName, WebhookBaseBranch)),
+			Ref:   github.String(WebhookBaseBranch),
+			SHA:   github.String(randomSHA()),
+			Repo:  g.repo,
+			User:  g.owner,
+		},
+	}
+	if WebhookEventName == "pull_request" && g.action == "closed" {
+		pr.State = github.String("closed")
+		pr.ClosedAt = g.now
+		if WebhookMerged {
+			pr.Merged = github.Bool(true)
+			pr.MergedAt = g.now
+			pr.MergedBy = g.sender
+			pr.MergeCommitSHA = github.String(randomSHA())
+		}
+	}
+	return pr
+}
+
+func (g *eventGenerator) pullRequestEvent() *github.PullRequestEvent {
+	event := &github.PullRequestEvent{
+		Action:       github.String(g.action),
+		Number:       github.Int(WebhookPRNumber),
+		PullRequest:  g.pr,
+		Repo:         g.repo,
+		Sender:       g.sender,
+		Organization: g.organization(),
+	}
+	if g.action == "synchronize" {
+		event.Before = github.String(randomSHA())
+		event.After = github.String(g.sha)
+	}
+	return event
+}
+
+func (g *eventGenerator) issueCommentEvent() *github.IssueCommentEvent {
+	return &github.IssueCommentEvent{
+		Action: github.String(g.action),
+		Issue: &github.Issue{
+			ID:        g.pr.ID,
+			Number:    g.pr.Number,
+			State:     g.pr.State,
+			Title:     g.pr.Title,
+			Body:      g.pr.Body,
+			User:      g.pr.User,
+			CreatedAt: g.now,
+			UpdatedAt: g.now,
+			HTMLURL:   g.pr.HTMLURL,
+			// the comments of PRs are delivered as issue comments with a link to the PR
+			PullRequestLinks: &github.PullRequestLinks{
+				HTMLURL: g.pr.HTMLURL,
+			},
+		},
+		Comment: &github.IssueComment{
+			ID:        github.Int64(10000),
+			Body:      github.String(WebhookComment),
+			User:      g.sender,
+			CreatedAt: g.now,
+			UpdatedAt: g.now,
+			HTMLURL:   github.String(fmt.Sprintf("%s#issuecomment-10000", g.pr.GetHTMLURL())),
+		},
+		Repo:         g.repo,
+		Sender:       g.sender,
+		Organization: g.organization(),
+	}
+}
+
+func (g *eventGenerator) checkRunEvent() *github.CheckRunEvent {
+	run := &github.CheckRun{
+		ID:        github.Int64(20000),
+		Name:      github.String(WebhookCheckName),
+		HeadSHA:   github.String(g.sha),
+		Status:    github.String("queued"),
+		StartedAt: g.now,
+		HTMLURL:   github.String(fmt.Sprintf("%s/runs/20000", g.repo.GetHTMLURL())),
+		CheckSuite: &github.CheckSuite{
+			ID:         github.Int64(30000),
+			HeadBranch: github.String(GithubBranchName),
+			HeadSHA:    github.String(g.sha),
+		},
+		PullRequests: []*github.PullRequest{{
+			ID:     g.pr.ID,
+			Number: g.pr.Number,
+			Head:   g.pr.Head,
+			Base:   g.pr.Base,
+		}},
+	}
+	if g.action == "completed" {
+		run.Status = github.String("completed")
+		run.Conclusion = github.String(WebhookConclusion)
+		run.CompletedAt = g.now
+	}
+	return &github.CheckRunEvent{
+		Action:   github.String(g.action),
+		CheckRun: run,
+		Repo:     g.repo,
+		Org:      g.organization(),
+		Sender:   g.sender,
+	}
+}
+
+func (g *eventGenerator) organization() *github.Organization {
+	return &github.Organization{ID: g.owner.ID, Login: g.owner.Login}
+}
+
+// randomSHA returns a random commit SHA for the parts of the payload not given via flags.
+func randomSHA() string {
+	b := make([]byte, 20)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
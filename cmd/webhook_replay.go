@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v52/github"
+	"github.com/spf13/cobra"
+)
+
+// maxResponseBody limits how much of the consumer response is reported.
+const maxResponseBody = 4096
+
+func init() {
+
+	webhookReplay.Flags().StringArrayVar(&WebhookFiles, "file", nil, "path to the payload to send, can be repeated")
+	webhookReplay.Flags().StringVar(&WebhookDir, "dir", "", "send all the .json payloads of the directory in the order of their names")
+	webhookReplay.Flags().StringVar(&WebhookEventName, "event", "", "the event type of the payloads, taken from the names of files recorded by webhook-serve when not specified")
+	webhookReplay.Flags().StringVar(&WebhookURL, "url", "", "the URL of the consumer")
+	addWebhookSecretFlags(webhookReplay)
+	webhookReplay.MarkFlagRequired("url")
+
+	webhookReplay.Run = func(cmd *cobra.Command, args []string) {
+		if err := ReplayWebhooks(); err != nil {
+			log.Fatalf("error when replaying webhooks: %v", err)
+		}
+	}
+}
+
+// WebhookSendResult is the response of the consumer to a single payload.
+type WebhookSendResult struct {
+	File       string  `json:"file,omitempty" yaml:"file,omitempty"`
+	Event      string  `json:"event" yaml:"event"`
+	Delivery   string  `json:"delivery" yaml:"delivery"`
+	StatusCode int     `json:"statusCode,omitempty" yaml:"statusCode,omitempty"`
+	Duration   float64 `json:"duration" yaml:"duration"`
+	Response   string  `json:"response,omitempty" yaml:"response,omitempty"`
+	Error      string  `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+func ReplayWebhooks() error {
+
+	secret, err := webhookSecret()
+	if err != nil {
+		return err
+	}
+
+	files := append([]string{}, WebhookFiles...)
+	if WebhookDir != "" {
+		entries, err := os.ReadDir(WebhookDir)
+		if err != nil {
+			return fmt.Errorf("error when reading payload directory: %v", err)
+		}
+		// the entries are sorted by name, which is the order webhook-serve recorded them in
+		for _, entry := range entries {
+			if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+				continue
+			}
+			files = append(files, filepath.Join(WebhookDir, entry.Name()))
+		}
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("none of the parameters 'file' or 'dir' specified")
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	results := []*WebhookSendResult{}
+	var failed int
+	for _, file := range files {
+		event := WebhookEventName
+		if event == "" {
+			if event = recordedEvent(file); event == "" {
+				return fmt.Errorf("cannot tell the event of %s, specify 'event'", file)
+			}
+		}
+		payload, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("error when reading payload: %v", err)
+		}
+
+		result := sendWebhook(client, WebhookURL, event, payload, secret)
+		result.File = file
+		results = append(results, result)
+		if result.Error != "" {
+			failed++
+		}
+	}
+
+	err = writeOutput(results, func() {
+		printWebhookSendResults(results)
+	})
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d payloads were not accepted by %s", failed, len(results), WebhookURL)
+	}
+	return nil
+}
+
+// recordedEvent returns the event type from the name of a file recorded by
+// webhook-serve, '<timestamp>-<event>-<delivery>.json', or an empty string.
+func recordedEvent(file string) string {
+	parts := strings.SplitN(strings.TrimSuffix(filepath.Base(file), ".json"), "-", 3)
+	if len(parts) != 3 {
+		return ""
+	}
+	if _, err := time.Parse(recordTimeLayout, parts[0]); err != nil {
+		return ""
+	}
+	return parts[1]
+}
+
+// sendWebhook posts the payload the way github delivers it: with the event
+// type, a new delivery GUID and, when there is a secret, the signature.
+// Errors are reported in the result so a batch can carry on.
+func sendWebhook(client *http.Client, url, event string, payload []byte, secret string) *WebhookSendResult {
+	result := &WebhookSendResult{Event: event, Delivery: newDeliveryID()}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "GitHub-Hookshot/ggh")
+	req.Header.Set(github.EventTypeHeader, event)
+	req.Header.Set(github.DeliveryIDHeader, result.Delivery)
+	if secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(payload)
+		req.Header.Set(github.SHA256SignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	start := time.Now()
+	res, err := client.Do(req)
+	result.Duration = time.Since(start).Seconds()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(res.Body, maxResponseBody))
+
+	result.StatusCode = res.StatusCode
+	result.Response = strings.TrimSpace(string(body))
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		result.Error = fmt.Sprintf("unexpected status %s", res.Status)
+	}
+	return result
+}
+
+// newDeliveryID returns a random version 4 UUID like the GUIDs github uses.
+func newDeliveryID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+func printWebhookSendResults(results []*WebhookSendResult) {
+	for _, r := range results {
+		name := r.File
+		if name == "" {
+			name = r.Event
+		}
+		if r.StatusCode == 0 {
+			fmt.Printf("%s\t%s\tfailed: %s\n", name, r.Delivery, r.Error)
+			continue
+		}
+		fmt.Printf("%s\t%s\t%d\t%.3fs\t%s\n", name, r.Delivery, r.StatusCode, r.Duration, r.Response)
+	}
+}
@@ -0,0 +1,302 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v52/github"
+	"github.com/spf13/cobra"
+)
+
+// recordTimeLayout is the timestamp prefix of the recorded payload files.
+const recordTimeLayout = "20060102T150405.000000000Z"
+
+// deliveryIDPattern matches the GUIDs github sends as delivery IDs. The
+// header is not covered by the signature, so it is checked before it
+// becomes part of a file name.
+var deliveryIDPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// forwardedHeaders are copied from the received delivery when forwarding it,
+// the signature stays valid as the body is forwarded unchanged.
+var forwardedHeaders = []string{
+	"Content-Type",
+	"User-Agent",
+	github.EventTypeHeader,
+	github.DeliveryIDHeader,
+	github.SHA256SignatureHeader,
+	github.SHA1SignatureHeader,
+	"X-GitHub-Hook-ID",
+	"X-GitHub-Hook-Installation-Target-ID",
+	"X-GitHub-Hook-Installation-Target-Type",
+}
+
+func init() {
+
+	webhookServe.Flags().StringVar(&WebhookListenAddr, "listen", ":8080", "the address the receiver listens on")
+	webhookServe.Flags().StringVar(&WebhookPath, "path", "/", "the path the deliveries are posted to")
+	addWebhookSecretFlags(webhookServe)
+	webhookServe.Flags().StringArrayVar(&WebhookExec, "exec", nil, "shell command run for an event in the form 'event=command', '*' matches all events, can be repeated")
+	webhookServe.Flags().StringVar(&WebhookForwardURL, "forward-url", "", "forward the verified deliveries to this URL - optional")
+	webhookServe.Flags().StringVar(&WebhookRecordDir, "record-dir", "", "save the payloads of the verified deliveries to this directory - optional")
+
+	webhookServe.Run = func(cmd *cobra.Command, args []string) {
+		if err := ServeWebhooks(); err != nil {
+			log.Fatalf("error when serving webhooks: %v", err)
+		}
+	}
+}
+
+// WebhookEvent is the JSON line logged for every delivery received by webhook-serve.
+type WebhookEvent struct {
+	Time          time.Time `json:"time"`
+	Delivery      string    `json:"delivery,omitempty"`
+	Event         string    `json:"event,omitempty"`
+	Action        string    `json:"action,omitempty"`
+	Repo          string    `json:"repo,omitempty"`
+	Sender        string    `json:"sender,omitempty"`
+	Status        int       `json:"status"`
+	Error         string    `json:"error,omitempty"`
+	RecordedTo    string    `json:"recordedTo,omitempty"`
+	ForwardStatus int       `json:"forwardStatus,omitempty"`
+	ExecCommand   string    `json:"execCommand,omitempty"`
+	ExecExitCode  *int      `json:"execExitCode,omitempty"`
+	ExecOutput    string    `json:"execOutput,omitempty"`
+}
+
+// webhookReceiver verifies the deliveries and hands them over to the
+// configured shell hooks, forward URL and record directory.
+type webhookReceiver struct {
+	secret   []byte
+	commands map[string]string
+	client   *http.Client
+
+	// the log lines of concurrent deliveries must not interleave
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func ServeWebhooks() error {
+
+	secret, err := webhookSecret()
+	if err != nil {
+		return err
+	}
+	commands, err := parseWebhookExec(WebhookExec)
+	if err != nil {
+		return err
+	}
+	if WebhookRecordDir != "" {
+		if err := os.MkdirAll(WebhookRecordDir, 0755); err != nil {
+			return fmt.Errorf("error when creating record directory: %v", err)
+		}
+	}
+	if secret == "" {
+		log.Println("no webhook secret configured, signatures are not verified")
+	}
+
+	receiver := &webhookReceiver{
+		secret:   []byte(secret),
+		commands: commands,
+		client:   &http.Client{Timeout: 30 * time.Second},
+		enc:      json.NewEncoder(os.Stdout),
+	}
+	mux := http.NewServeMux()
+	mux.Handle(WebhookPath, receiver)
+
+	log.Printf("listening for webhooks on %s%s", WebhookListenAddr, WebhookPath)
+	return http.ListenAndServe(WebhookListenAddr, mux)
+}
+
+// parseWebhookExec parses the 'event=command' pairs given via --exec.
+func parseWebhookExec(specs []string) (map[string]string, error) {
+	commands := map[string]string{}
+	for _, spec := range specs {
+		event, command, ok := strings.Cut(spec, "=")
+		if !ok || event == "" || command == "" {
+			return nil, fmt.Errorf("invalid exec '%s', expected 'event=command'", spec)
+		}
+		commands[event] = command
+	}
+	return commands, nil
+}
+
+func (rcv *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e := &WebhookEvent{
+		Time:     time.Now().UTC(),
+		Delivery: github.DeliveryID(r),
+		Event:    github.WebHookType(r),
+	}
+	defer rcv.log(e)
+
+	if r.Method != http.MethodPost {
+		e.Status, e.Error = http.StatusMethodNotAllowed, fmt.Sprintf("unsupported method %s", r.Method)
+		http.Error(w, e.Error, e.Status)
+		return
+	}
+
+	signature := r.Header.Get(github.SHA256SignatureHeader)
+	if len(rcv.secret) > 0 && signature == "" {
+		e.Status, e.Error = http.StatusUnauthorized, fmt.Sprintf("missing %s header", github.SHA256SignatureHeader)
+		http.Error(w, e.Error, e.Status)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		e.Status, e.Error = http.StatusBadRequest, fmt.Sprintf("error when reading body: %v", err)
+		http.Error(w, e.Error, e.Status)
+		return
+	}
+	payload, err := github.ValidatePayloadFromBody(r.Header.Get("Content-Type"), bytes.NewReader(body), signature, rcv.secret)
+	if err != nil {
+		e.Status, e.Error = http.StatusUnauthorized, err.Error()
+		http.Error(w, e.Error, e.Status)
+		return
+	}
+
+	if _, err := github.ParseWebHook(e.Event, payload); err != nil {
+		e.Status, e.Error = http.StatusBadRequest, err.Error()
+		http.Error(w, e.Error, e.Status)
+		return
+	}
+	// the typed events have no common accessors for these
+	var common struct {
+		Action     string `json:"action"`
+		Repository struct {
+			FullName string `json:"full_name"`
+		} `json:"repository"`
+		Organization struct {
+			Login string `json:"login"`
+		} `json:"organization"`
+		Sender struct {
+			Login string `json:"login"`
+		} `json:"sender"`
+	}
+	json.Unmarshal(payload, &common)
+	e.Action = common.Action
+	e.Repo = common.Repository.FullName
+	if e.Repo == "" {
+		e.Repo = common.Organization.Login
+	}
+	e.Sender = common.Sender.Login
+
+	if WebhookRecordDir != "" {
+		path, err := recordPayload(WebhookRecordDir, e, payload)
+		if err != nil {
+			e.Error = err.Error()
+		}
+		e.RecordedTo = path
+	}
+
+	if WebhookForwardURL != "" {
+		status, err := rcv.forward(r, body)
+		e.ForwardStatus = status
+		if err != nil {
+			e.Status, e.Error = http.StatusBadGateway, err.Error()
+			http.Error(w, e.Error, e.Status)
+			return
+		}
+	}
+
+	e.Status = http.StatusOK
+	w.WriteHeader(e.Status)
+
+	command, ok := rcv.commands[e.Event]
+	if !ok {
+		command, ok = rcv.commands["*"]
+	}
+	if ok {
+		// github gives up after 10 seconds, so the hook must not delay the response
+		go rcv.exec(command, e, payload)
+	}
+}
+
+// recordPayload saves the payload so it can be replayed with webhook-replay,
+// the file names sort in the order the deliveries were received.
+func recordPayload(dir string, e *WebhookEvent, payload []byte) (string, error) {
+	if !deliveryIDPattern.MatchString(e.Delivery) {
+		return "", fmt.Errorf("not recording payload with invalid delivery id '%s'", e.Delivery)
+	}
+	name := fmt.Sprintf("%s-%s-%s.json", e.Time.Format(recordTimeLayout), e.Event, e.Delivery)
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, payload, 0644); err != nil {
+		return "", fmt.Errorf("error when recording payload: %v", err)
+	}
+	return path, nil
+}
+
+// forward posts the body unchanged to the forward URL together with the github headers.
+func (rcv *webhookReceiver) forward(r *http.Request, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodPost, WebhookForwardURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	for _, h := range forwardedHeaders {
+		if v := r.Header.Get(h); v != "" {
+			req.Header.Set(h, v)
+		}
+	}
+	res, err := rcv.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("error when forwarding to %s: %v", WebhookForwardURL, err)
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, res.Body)
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return res.StatusCode, fmt.Errorf("forwarding to %s failed with status %s", WebhookForwardURL, res.Status)
+	}
+	return res.StatusCode, nil
+}
+
+// exec runs the shell hook with the payload on stdin and the delivery
+// details in the environment, the result is logged as a separate line.
+func (rcv *webhookReceiver) exec(command string, delivery *WebhookEvent, payload []byte) {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Env = append(os.Environ(),
+		"GITHUB_EVENT="+delivery.Event,
+		"GITHUB_DELIVERY="+delivery.Delivery,
+		"GITHUB_ACTION="+delivery.Action,
+		"GITHUB_REPOSITORY="+delivery.Repo,
+	)
+	out, err := cmd.CombinedOutput()
+
+	exitCode := 0
+	e := &WebhookEvent{
+		Time:         time.Now().UTC(),
+		Delivery:     delivery.Delivery,
+		Event:        delivery.Event,
+		Action:       delivery.Action,
+		Repo:         delivery.Repo,
+		Status:       delivery.Status,
+		ExecCommand:  command,
+		ExecExitCode: &exitCode,
+		ExecOutput:   strings.TrimSpace(string(out)),
+	}
+	if err != nil {
+		exitCode = -1
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			exitCode = exitErr.ExitCode()
+		}
+		e.Error = err.Error()
+	}
+	rcv.log(e)
+}
+
+func (rcv *webhookReceiver) log(e *WebhookEvent) {
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	if err := rcv.enc.Encode(e); err != nil {
+		log.Printf("error when logging webhook event: %v", err)
+	}
+}